@@ -0,0 +1,197 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tarPath writes the file or directory at source into tw. Entries are
+// named relative to the parent of source when includeBase is true and
+// relative to source itself otherwise.
+func tarPath(tw *tar.Writer, source string, includeBase bool) error {
+	source = filepath.Clean(source)
+	base := source
+	if includeBase {
+		base = filepath.Dir(source)
+	}
+
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path) // nolint: gosec
+		if err != nil {
+			return err
+		}
+		defer file.Close() // nolint: errcheck
+		_, err = io.Copy(tw, file)
+		return err
+	})
+}
+
+// tarStream runs write in the background and returns a reader for the
+// tar stream it produces. Any error returned by write is reported when
+// reading.
+func tarStream(write func(*tar.Writer) error) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(writer)
+		err := write(tw)
+		if err == nil {
+			err = tw.Close()
+		}
+		writer.CloseWithError(err) // nolint: errcheck
+	}()
+	return reader
+}
+
+// writeTarFile writes a regular file named name containing data into tw.
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     filepath.ToSlash(name),
+		Mode:     0644,
+		Size:     int64(len(data)),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// extractTar extracts the tar stream read from reader into the directory
+// dir. Entries which would be written outside of dir are rejected.
+func extractTar(reader io.Reader, dir string) error {
+	dir = filepath.Clean(dir)
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !within(dir, target) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+		if err := checkParents(dir, target); err != nil {
+			return err
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+			continue
+		case tar.TypeReg, tar.TypeRegA, tar.TypeSymlink:
+		default:
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		// Anything already at target is replaced rather than written
+		// through, in case it is a symlink.
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if header.Typeflag == tar.TypeSymlink {
+			link := filepath.FromSlash(header.Linkname)
+			if !filepath.IsAbs(link) {
+				link = filepath.Join(filepath.Dir(target), link)
+			}
+			if !within(dir, filepath.Clean(link)) {
+				return fmt.Errorf("invalid link in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeFileFrom(target, tr, mode); err != nil {
+			return err
+		}
+	}
+}
+
+// within returns true if path is dir or is inside of it. Both must be
+// clean.
+func within(dir string, path string) bool {
+	relative, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// checkParents returns an error if any directory between dir and target
+// is a symlink, so an archive cannot write outside of dir through a link
+// it created earlier.
+func checkParents(dir string, target string) error {
+	for parent := filepath.Dir(target); parent != dir && within(dir, parent); parent = filepath.Dir(parent) {
+		info, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("invalid path in archive, %s is a symlink", parent)
+		}
+	}
+	return nil
+}
+
+// writeFileFrom creates the file at path with the content of reader.
+func writeFileFrom(path string, reader io.Reader, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode) // nolint: gosec
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close() // nolint: errcheck
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,115 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type ArchiveTest struct{}
+
+var _ = Suite(&ArchiveTest{})
+
+func readTar(c *C, reader io.Reader) map[string]string {
+	entries := map[string]string{}
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(tr)
+		c.Assert(err, IsNil)
+		entries[header.Name] = string(data)
+		if header.Typeflag == tar.TypeSymlink {
+			entries[header.Name] = "-> " + header.Linkname
+		}
+	}
+}
+
+func (s *ArchiveTest) TestTarPath(c *C) {
+	dir := filepath.Join(c.MkDir(), "base")
+	c.Assert(os.MkdirAll(filepath.Join(dir, "sub"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "sub", "file"), []byte("content"), 0644), IsNil)
+	c.Assert(os.Symlink("sub/file", filepath.Join(dir, "link")), IsNil)
+
+	stream := tarStream(func(tw *tar.Writer) error { return tarPath(tw, dir, false) })
+	c.Assert(readTar(c, stream), DeepEquals, map[string]string{
+		"sub/": "", "sub/file": "content", "link": "-> sub/file",
+	})
+
+	stream = tarStream(func(tw *tar.Writer) error { return tarPath(tw, dir, true) })
+	c.Assert(readTar(c, stream), DeepEquals, map[string]string{
+		"base/": "", "base/sub/": "", "base/sub/file": "content", "base/link": "-> sub/file",
+	})
+}
+
+func (s *ArchiveTest) TestTarStreamError(c *C) {
+	stream := tarStream(func(tw *tar.Writer) error {
+		return tarPath(tw, filepath.Join(c.MkDir(), "missing"), false)
+	})
+	_, err := ioutil.ReadAll(stream)
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *ArchiveTest) TestExtractTar(c *C) {
+	source := filepath.Join(c.MkDir(), "base")
+	c.Assert(os.MkdirAll(filepath.Join(source, "sub"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(source, "sub", "file"), []byte("content"), 0600), IsNil)
+	c.Assert(os.Symlink("sub/file", filepath.Join(source, "link")), IsNil)
+
+	dir := c.MkDir()
+	stream := tarStream(func(tw *tar.Writer) error { return tarPath(tw, source, true) })
+	c.Assert(extractTar(stream, dir), IsNil)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "base", "link"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "content")
+	stat, err := os.Stat(filepath.Join(dir, "base", "sub", "file"))
+	c.Assert(err, IsNil)
+	c.Assert(stat.Mode().Perm(), Equals, os.FileMode(0600))
+}
+
+func (s *ArchiveTest) TestExtractTarOutsideDirectory(c *C) {
+	stream := tarStream(func(tw *tar.Writer) error {
+		return writeTarFile(tw, "../escape", []byte("data"))
+	})
+	dir := filepath.Join(c.MkDir(), "dir")
+	c.Assert(extractTar(stream, dir), ErrorMatches, "invalid path in archive: ../escape")
+	_, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *ArchiveTest) TestExtractTarSymlinkOutsideDirectory(c *C) {
+	outside := c.MkDir()
+	for _, link := range []string{outside, "../../" + filepath.Base(outside)} {
+		stream := tarStream(func(tw *tar.Writer) error {
+			header := &tar.Header{Typeflag: tar.TypeSymlink, Name: "x", Linkname: link}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			return writeTarFile(tw, "x/passwd", []byte("data"))
+		})
+		dir := c.MkDir()
+		c.Assert(extractTar(stream, dir), ErrorMatches, "invalid link in archive: x -> .*")
+		_, err := os.Stat(filepath.Join(outside, "passwd"))
+		c.Assert(os.IsNotExist(err), Equals, true)
+	}
+}
+
+func (s *ArchiveTest) TestExtractTarThroughExistingSymlink(c *C) {
+	outside := c.MkDir()
+	dir := c.MkDir()
+	c.Assert(os.Symlink(outside, filepath.Join(dir, "x")), IsNil)
+	stream := tarStream(func(tw *tar.Writer) error {
+		return writeTarFile(tw, "x/passwd", []byte("data"))
+	})
+	c.Assert(extractTar(stream, dir), ErrorMatches, "invalid path in archive, .*x is a symlink")
+	_, err := os.Stat(filepath.Join(outside, "passwd"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
@@ -0,0 +1,213 @@
+package dockertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// dockerHubServer is the key used by Docker for credentials
+	// belonging to Docker Hub.
+	dockerHubServer = "https://index.docker.io/v1/"
+
+	// credentialsNotFound is reported by credential helpers which have
+	// no credentials stored for the requested server.
+	credentialsNotFound = "credentials not found in native keychain"
+
+	// identityTokenUsername is returned by credential helpers in place
+	// of a username when the secret is an identity token.
+	identityTokenUsername = "<token>"
+)
+
+// dockerConfigFile is the subset of ~/.docker/config.json used to
+// resolve registry credentials.
+type dockerConfigFile struct {
+	Auths       map[string]types.AuthConfig `json:"auths"`
+	CredsStore  string                      `json:"credsStore"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+}
+
+// credentialHelperResponse is produced by `docker-credential-<name> get`.
+type credentialHelperResponse struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// dockerConfigDir returns the directory containing config.json. It
+// honors $DOCKER_CONFIG and otherwise uses ~/.docker.
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// registryHostname converts a key from the auths section of
+// config.json, which may be a url, into a hostname.
+func registryHostname(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	return strings.SplitN(server, "/", 2)[0]
+}
+
+// registryServer returns the server which credentials for image are
+// stored under.
+func registryServer(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+	domain := reference.Domain(named)
+	if domain == "docker.io" {
+		return dockerHubServer, nil
+	}
+	return domain, nil
+}
+
+// encodeAuth encodes auth in the form expected by the X-Registry-Auth
+// header.
+func encodeAuth(auth *types.AuthConfig) (string, error) {
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// readDockerConfig reads config.json from dir. A missing file is not an
+// error, it simply contains no credentials.
+func readDockerConfig(dir string) (*dockerConfigFile, error) {
+	config := &dockerConfigFile{}
+	if dir == "" {
+		return config, nil
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", filepath.Join(dir, "config.json"))
+	}
+	return config, nil
+}
+
+// credentialHelper runs docker-credential-<helper> to retrieve the
+// credentials stored for server. nil is returned if the helper has no
+// credentials for server.
+func credentialHelper(ctx context.Context, helper string, server string) (*types.AuthConfig, error) {
+	stdout := &bytes.Buffer{}
+	command := exec.CommandContext(ctx, "docker-credential-"+helper, "get") // nolint: gosec
+	command.Stdin = strings.NewReader(server)
+	command.Stdout = stdout
+	if err := command.Run(); err != nil {
+		if strings.Contains(stdout.String(), credentialsNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "credential helper %s failed: %s", helper, strings.TrimSpace(stdout.String()))
+	}
+
+	response := &credentialHelperResponse{}
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, errors.Wrapf(err, "credential helper %s returned invalid output", helper)
+	}
+	auth := &types.AuthConfig{ServerAddress: server}
+	if response.Username == identityTokenUsername {
+		auth.IdentityToken = response.Secret
+	} else {
+		auth.Username = response.Username
+		auth.Password = response.Secret
+	}
+	return auth, nil
+}
+
+// fromAuths converts an entry in the auths section of config.json,
+// where the username and password may be encoded in the auth field.
+func fromAuths(server string, entry types.AuthConfig) (*types.AuthConfig, error) {
+	auth := &types.AuthConfig{
+		Username:      entry.Username,
+		Password:      entry.Password,
+		ServerAddress: server,
+		IdentityToken: entry.IdentityToken,
+	}
+	if entry.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid auth for %s", server)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid auth for %s", server)
+		}
+		auth.Username, auth.Password = parts[0], parts[1]
+	}
+	return auth, nil
+}
+
+// RegistryAuth resolves the credentials which should be used to pull
+// image. Credentials are read from config.json in the client's Docker
+// config directory, consulting credHelpers, then credsStore and finally
+// auths. nil is returned if no credentials are configured.
+func (d *DockerClient) RegistryAuth(ctx context.Context, image string) (*types.AuthConfig, error) {
+	server, err := registryServer(image)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := d.dockerConfig
+	if dir == "" {
+		dir = dockerConfigDir()
+	}
+	config, err := readDockerConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname := registryHostname(server)
+	if helper, ok := config.CredHelpers[hostname]; ok {
+		return credentialHelper(ctx, helper, server)
+	}
+	if config.CredsStore != "" {
+		return credentialHelper(ctx, config.CredsStore, server)
+	}
+	for key, entry := range config.Auths {
+		if registryHostname(key) == hostname {
+			return fromAuths(server, entry)
+		}
+	}
+	return nil, nil
+}
+
+// encodedRegistryAuth returns the X-Registry-Auth value to send when
+// pulling image. Explicit credentials take precedence over those found
+// in the Docker config.
+func (d *DockerClient) encodedRegistryAuth(ctx context.Context, image string, auth *types.AuthConfig) (string, error) {
+	if auth == nil {
+		resolved, err := d.RegistryAuth(ctx, image)
+		if err != nil {
+			return "", err
+		}
+		auth = resolved
+	}
+	if auth == nil {
+		return "", nil
+	}
+	return encodeAuth(auth)
+}
@@ -0,0 +1,175 @@
+package dockertest
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+const credentialHelperScript = `#!/bin/sh
+read server
+if [ "$server" = "registry.example.com" ]; then
+	echo '{"ServerURL":"registry.example.com","Username":"helper","Secret":"secret"}'
+	exit 0
+fi
+if [ "$server" = "tokens.example.com" ]; then
+	echo '{"ServerURL":"tokens.example.com","Username":"<token>","Secret":"token"}'
+	exit 0
+fi
+echo "credentials not found in native keychain"
+exit 1
+`
+
+type AuthTest struct {
+	dir  string
+	path string
+}
+
+var _ = Suite(&AuthTest{})
+
+func (s *AuthTest) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+	s.path = os.Getenv("PATH")
+	helper := filepath.Join(s.dir, "docker-credential-fake")
+	c.Assert(ioutil.WriteFile(helper, []byte(credentialHelperScript), 0700), IsNil)
+	c.Assert(os.Setenv("PATH", s.dir+string(os.PathListSeparator)+s.path), IsNil)
+}
+
+func (s *AuthTest) TearDownTest(c *C) {
+	c.Assert(os.Setenv("PATH", s.path), IsNil)
+}
+
+func (s *AuthTest) writeConfig(c *C, config string) {
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "config.json"), []byte(config), 0600), IsNil)
+}
+
+func (s *AuthTest) newClient(c *C) (*DockerClient, *fakeengine.Server) {
+	server, err := fakeengine.NewServer()
+	c.Assert(err, IsNil)
+	dc, err := NewClientWithOptions(WithHost(server.Host()), WithDockerConfig(s.dir))
+	c.Assert(err, IsNil)
+	return dc, server
+}
+
+func (s *AuthTest) TestRegistryServer(c *C) {
+	for image, expected := range map[string]string{
+		"nginx":                      dockerHubServer,
+		"library/nginx:latest":       dockerHubServer,
+		"registry.example.com/foo:1": "registry.example.com",
+		"localhost:5000/foo/bar@sha256:" + sha256Zero: "localhost:5000",
+	} {
+		server, err := registryServer(image)
+		c.Assert(err, IsNil)
+		c.Assert(server, Equals, expected)
+	}
+	_, err := registryServer("Invalid Reference")
+	c.Assert(err, NotNil)
+}
+
+func (s *AuthTest) TestRegistryHostname(c *C) {
+	c.Assert(registryHostname(dockerHubServer), Equals, "index.docker.io")
+	c.Assert(registryHostname("http://localhost:5000/v2/"), Equals, "localhost:5000")
+	c.Assert(registryHostname("registry.example.com"), Equals, "registry.example.com")
+}
+
+func (s *AuthTest) TestRegistryAuthNoConfig(c *C) {
+	dc, server := s.newClient(c)
+	defer server.Close() // nolint: errcheck
+	auth, err := dc.RegistryAuth(context.Background(), "nginx")
+	c.Assert(err, IsNil)
+	c.Assert(auth, IsNil)
+}
+
+func (s *AuthTest) TestRegistryAuthInvalidConfig(c *C) {
+	dc, server := s.newClient(c)
+	defer server.Close() // nolint: errcheck
+	s.writeConfig(c, "{")
+	_, err := dc.RegistryAuth(context.Background(), "nginx")
+	c.Assert(err, ErrorMatches, "failed to parse .*config.json.*")
+}
+
+func (s *AuthTest) TestRegistryAuthAuths(c *C) {
+	dc, server := s.newClient(c)
+	defer server.Close() // nolint: errcheck
+	encoded := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	s.writeConfig(c, `{"auths": {"https://index.docker.io/v1/": {"auth": "`+encoded+`"}}}`)
+
+	auth, err := dc.RegistryAuth(context.Background(), "nginx")
+	c.Assert(err, IsNil)
+	c.Assert(auth, DeepEquals, &types.AuthConfig{
+		Username: "user", Password: "pass", ServerAddress: dockerHubServer,
+	})
+
+	auth, err = dc.RegistryAuth(context.Background(), "registry.example.com/foo")
+	c.Assert(err, IsNil)
+	c.Assert(auth, IsNil)
+}
+
+func (s *AuthTest) TestRegistryAuthCredHelpers(c *C) {
+	dc, server := s.newClient(c)
+	defer server.Close() // nolint: errcheck
+	s.writeConfig(c, `{
+		"credsStore": "missing",
+		"credHelpers": {
+			"registry.example.com": "fake",
+			"tokens.example.com": "fake",
+			"other.example.com": "fake"
+		}
+	}`)
+
+	auth, err := dc.RegistryAuth(context.Background(), "registry.example.com/foo")
+	c.Assert(err, IsNil)
+	c.Assert(auth, DeepEquals, &types.AuthConfig{
+		Username: "helper", Password: "secret", ServerAddress: "registry.example.com",
+	})
+
+	auth, err = dc.RegistryAuth(context.Background(), "tokens.example.com/foo")
+	c.Assert(err, IsNil)
+	c.Assert(auth, DeepEquals, &types.AuthConfig{
+		IdentityToken: "token", ServerAddress: "tokens.example.com",
+	})
+
+	auth, err = dc.RegistryAuth(context.Background(), "other.example.com/foo")
+	c.Assert(err, IsNil)
+	c.Assert(auth, IsNil)
+
+	// Falls back to credsStore, which does not exist.
+	_, err = dc.RegistryAuth(context.Background(), "nginx")
+	c.Assert(err, ErrorMatches, "credential helper missing failed.*")
+}
+
+func (s *AuthTest) TestRunContainerWithConfigCredentials(c *C) {
+	dc, server := s.newClient(c)
+	defer server.Close() // nolint: errcheck
+	s.writeConfig(c, `{"credsStore": "fake"}`)
+	image := "registry.example.com/private:1"
+	server.AddRemoteImage(image, &fakeengine.Image{Username: "helper", Password: "secret"})
+
+	_, err := dc.RunContainer(context.Background(), NewClientInput(image))
+	c.Assert(err, IsNil)
+	c.Assert(server.RegistryAuths()[0].Username, Equals, "helper")
+}
+
+func (s *AuthTest) TestRunContainerWithExplicitCredentials(c *C) {
+	dc, server := s.newClient(c)
+	defer server.Close() // nolint: errcheck
+	s.writeConfig(c, `{"credsStore": "fake"}`)
+	image := "registry.example.com/private:1"
+	server.AddRemoteImage(image, &fakeengine.Image{Username: "explicit", Password: "password"})
+
+	input := NewClientInput(image)
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, ErrorMatches, ".*incorrect username or password")
+
+	input.RegistryAuth = &types.AuthConfig{Username: "explicit", Password: "password"}
+	_, err = dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+}
+
+const sha256Zero = "0000000000000000000000000000000000000000000000000000000000000000"
@@ -0,0 +1,163 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/docker/docker/api/types"
+)
+
+var (
+	// ErrBuildContextNotProvided is returned by BuildImage if neither
+	// ContextDir nor Context was provided.
+	ErrBuildContextNotProvided = errors.New("build context not provided")
+
+	// ErrBuildImageIDNotFound is returned by BuildImage if Docker did
+	// not report the id of the image it built.
+	ErrBuildImageIDNotFound = errors.New("unable to determine the id of the built image")
+
+	// ErrBuildContextUsed is returned by BuildImage if Context has
+	// already been read by an earlier build.
+	ErrBuildContextUsed = errors.New("build context has already been used")
+
+	successfullyBuilt = regexp.MustCompile(`Successfully built ([0-9a-f]+)`)
+)
+
+// BuildInput is used to provide inputs to the BuildImage function.
+type BuildInput struct {
+	// ContextDir is a directory on the host which will be sent to
+	// Docker as the build context.
+	ContextDir string
+
+	// Context is a tar stream to use as the build context. It is used
+	// instead of ContextDir if provided. The stream is read to the end
+	// so a BuildInput with a Context may only be built once, use
+	// ContextDir to build the same input more than once.
+	Context io.Reader
+
+	// Dockerfile is the path to the Dockerfile within the build
+	// context. Docker uses "Dockerfile" if not provided.
+	Dockerfile string
+
+	// BuildArgs are passed to ARG instructions in the Dockerfile.
+	BuildArgs map[string]string
+
+	// Target is the name of the build stage to build.
+	Target string
+
+	// Tags are applied to the resulting image.
+	Tags []string
+
+	// Labels are applied to the resulting image.
+	Labels map[string]string
+
+	// Progress, if set, receives the build output.
+	Progress Progress
+
+	// contextUsed is true once Context has been sent to Docker.
+	contextUsed bool
+}
+
+// NewBuildInput produces a *BuildInput struct which will build the
+// provided context directory.
+func NewBuildInput(contextDir string) *BuildInput {
+	return &BuildInput{
+		ContextDir: contextDir,
+		BuildArgs:  map[string]string{},
+		Labels:     map[string]string{},
+	}
+}
+
+// BuildError is returned when Docker reports an error while building
+// an image, such as a failing RUN instruction.
+type BuildError struct {
+	Code    int
+	Message string
+}
+
+func (e *BuildError) Error() string {
+	return fmt.Sprintf("failed to build image: %s", e.Message)
+}
+
+// ImageBuildOptions converts *BuildInput into the options which may be
+// passed to the ImageBuild() API call. The dockertest label is always
+// applied to the resulting image.
+func (b *BuildInput) ImageBuildOptions() types.ImageBuildOptions {
+	args := map[string]*string{}
+	for key, value := range b.BuildArgs {
+		value := value
+		args[key] = &value
+	}
+	labels := map[string]string{"dockertest": "1"}
+	for key, value := range b.Labels {
+		labels[key] = value
+	}
+	return types.ImageBuildOptions{
+		Tags:       b.Tags,
+		Dockerfile: b.Dockerfile,
+		BuildArgs:  args,
+		Target:     b.Target,
+		Labels:     labels,
+		Remove:     true,
+	}
+}
+
+// BuildImage builds an image and returns its id. Build output is decoded
+// so errors reported by Docker are returned as a *BuildError.
+func (d *DockerClient) BuildImage(ctx context.Context, input *BuildInput) (string, error) {
+	buildContext := input.Context
+	if buildContext != nil {
+		if input.contextUsed {
+			return "", ErrBuildContextUsed
+		}
+		input.contextUsed = true
+	} else {
+		if input.ContextDir == "" {
+			return "", ErrBuildContextNotProvided
+		}
+		stream := tarStream(func(tw *tar.Writer) error {
+			return tarPath(tw, input.ContextDir, false)
+		})
+		defer stream.Close() // nolint: errcheck
+		buildContext = stream
+	}
+
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	response, err := d.docker.ImageBuild(ctx, buildContext, input.ImageBuildOptions())
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	id := ""
+	err = readProgress(response.Body, func(message *ProgressMessage) {
+		if message.Aux != nil {
+			aux := struct{ ID string }{}
+			if json.Unmarshal(*message.Aux, &aux) == nil && aux.ID != "" {
+				id = aux.ID
+			}
+		}
+		if match := successfullyBuilt.FindStringSubmatch(message.Stream); match != nil && id == "" {
+			id = match[1]
+		}
+		if input.Progress != nil {
+			input.Progress(message)
+		}
+	})
+	if streamErr, ok := err.(*StreamError); ok {
+		return "", &BuildError{Code: streamErr.Code, Message: streamErr.Message}
+	}
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", ErrBuildImageIDNotFound
+	}
+	return id, nil
+}
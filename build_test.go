@@ -0,0 +1,114 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type BuildTest struct{}
+
+var _ = Suite(&BuildTest{})
+
+func writeDockerfile(c *C, content string) string {
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(content), 0644), IsNil)
+	return dir
+}
+
+func (s *BuildTest) TestImageBuildOptions(c *C) {
+	input := NewBuildInput("")
+	input.BuildArgs["a"] = "1"
+	input.Labels["b"] = "2"
+	input.Tags = []string{"tag"}
+	options := input.ImageBuildOptions()
+	c.Assert(*options.BuildArgs["a"], Equals, "1")
+	c.Assert(options.Labels, DeepEquals, map[string]string{"dockertest": "1", "b": "2"})
+	c.Assert(options.Tags, DeepEquals, []string{"tag"})
+	c.Assert(options.Remove, Equals, true)
+}
+
+func (s *BuildTest) TestBuildImage(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+
+	streams := []string{}
+	input := NewBuildInput(writeDockerfile(c, "FROM scratch\nCMD [\"/app\"]\n"))
+	input.Tags = []string{"built"}
+	input.Progress = func(message *ProgressMessage) {
+		streams = append(streams, message.Stream)
+	}
+	id, err := dc.BuildImage(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(id, Matches, "sha256:[0-9a-f]{64}")
+	c.Assert(server.HasImage("built"), Equals, true)
+	c.Assert(server.HasImage(id), Equals, true)
+	c.Assert(strings.Join(streams, ""), Matches, "(?s)Step 1/2 : FROM scratch.*")
+}
+
+func (s *BuildTest) TestBuildImageContextStream(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+
+	input := &BuildInput{
+		Context: tarStream(func(tw *tar.Writer) error {
+			return tarPath(tw, writeDockerfile(c, "FROM scratch\n"), false)
+		}),
+		Dockerfile: "Dockerfile",
+	}
+	id, err := dc.BuildImage(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(server.HasImage(id), Equals, true)
+
+	// The stream has been read so it cannot be built again.
+	_, err = dc.BuildImage(context.Background(), input)
+	c.Assert(err, Equals, ErrBuildContextUsed)
+}
+
+func (s *BuildTest) TestBuildImageContextNotProvided(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	_, err := dc.BuildImage(context.Background(), &BuildInput{})
+	c.Assert(err, Equals, ErrBuildContextNotProvided)
+}
+
+func (s *BuildTest) TestBuildImageError(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	_, err := dc.BuildImage(context.Background(), NewBuildInput(writeDockerfile(c, "FROM scratch\nRUN false\n")))
+	c.Assert(err, DeepEquals, &BuildError{
+		Message: "The command '/bin/sh -c false' returned a non-zero code: 1"})
+	c.Assert(err, ErrorMatches, "failed to build image: .*")
+}
+
+func (s *BuildTest) TestRunContainerWithBuild(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddRemoteImage("base", &fakeengine.Image{})
+
+	input := NewClientInput("")
+	input.Build = NewBuildInput(writeDockerfile(c, "FROM base\nLABEL role=app\n"))
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(info.JSON.Image, Matches, "sha256:[0-9a-f]{64}")
+	c.Assert(info.JSON.Config.Labels["role"], Equals, "app")
+
+	containers, err := dc.docker.ContainerList(context.Background(), types.ContainerListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", "dockertest=1")),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(containers, HasLen, 1)
+
+	// The input is not modified so it can be used again.
+	c.Assert(input.Image, Equals, "")
+	again, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(again.JSON.Image, Equals, info.JSON.Image)
+}
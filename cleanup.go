@@ -0,0 +1,191 @@
+package dockertest
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/crewjam/errset"
+	"github.com/docker/docker/api/types"
+)
+
+// SessionLabel is the label used to record the session a container,
+// network or volume was created in.
+const SessionLabel = "dockertest.session"
+
+// CleanupPolicy selects the resources which Cleanup removes.
+type CleanupPolicy struct {
+	// Labels which resources must have to be removed. If neither this
+	// nor Session is set only resources with the dockertest label are
+	// removed.
+	Labels map[string]string
+
+	// OlderThan, if set, limits removal to resources created at least
+	// this long ago.
+	OlderThan time.Duration
+
+	// Session, if set, limits removal to resources created in the
+	// session with this id.
+	Session string
+}
+
+// NewCleanupPolicy produces a *CleanupPolicy which selects every
+// resource with the dockertest label.
+func NewCleanupPolicy() *CleanupPolicy {
+	return &CleanupPolicy{Labels: map[string]string{"dockertest": "1"}}
+}
+
+// labels returns every label a resource must have to be removed.
+func (p *CleanupPolicy) labels() map[string]string {
+	labels := map[string]string{}
+	for key, value := range p.Labels {
+		labels[key] = value
+	}
+	if len(labels) == 0 && p.Session == "" {
+		labels["dockertest"] = "1"
+	}
+	if p.Session != "" {
+		labels[SessionLabel] = p.Session
+	}
+	return labels
+}
+
+// CleanupReport lists the resources removed by Cleanup.
+type CleanupReport struct {
+	Containers []string
+	Networks   []string
+	Volumes    []string
+}
+
+// Cleanup removes the containers, networks and volumes selected by the
+// policy. Containers are removed first so the networks and volumes they
+// use can be removed afterwards. Resources are removed concurrently and
+// any errors are combined into one, the returned report lists everything
+// which was removed even if an error is returned. A nil policy is the
+// same as NewCleanupPolicy. Reaper containers, see Session.StartReaper,
+// are never removed.
+func (d *DockerClient) Cleanup(ctx context.Context, policy *CleanupPolicy) (*CleanupReport, error) {
+	if policy == nil {
+		policy = NewCleanupPolicy()
+	}
+	report := &CleanupReport{}
+	errout := errset.ErrSet{}
+	labels := policy.labels()
+	now := time.Now()
+
+	containers, err := d.cleanupContainers(ctx, labels, policy.OlderThan, now)
+	if err != nil {
+		errout = append(errout, err)
+	}
+	var errs errset.ErrSet
+	report.Containers, errs = removeAll(containers, func(id string) error {
+		return d.RemoveContainer(ctx, id)
+	})
+	errout = append(errout, errs...)
+
+	networks, err := d.cleanupNetworks(ctx, labels, policy.OlderThan, now)
+	if err != nil {
+		errout = append(errout, err)
+	}
+	report.Networks, errs = removeAll(networks, func(id string) error {
+		return d.RemoveNetwork(ctx, id)
+	})
+	errout = append(errout, errs...)
+
+	volumes, err := d.cleanupVolumes(ctx, labels, policy.OlderThan, now)
+	if err != nil {
+		errout = append(errout, err)
+	}
+	report.Volumes, errs = removeAll(volumes, func(name string) error {
+		return d.RemoveVolume(ctx, name)
+	})
+	errout = append(errout, errs...)
+
+	return report, errout.ReturnValue()
+}
+
+func (d *DockerClient) cleanupContainers(ctx context.Context, labels map[string]string, age time.Duration, now time.Time) ([]string, error) {
+	input := &ClientInput{Labels: labels, All: true, OlderThan: age}
+	listctx, cancel := d.callContext(ctx)
+	defer cancel()
+	containers, err := d.docker.ContainerList(listctx, types.ContainerListOptions{All: true, Filters: input.FilterArgs()})
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, entry := range containers {
+		// Reapers belong to sessions which may still be running, each
+		// reaper exits by itself once its session ends.
+		if _, ok := entry.Labels[ReaperLabel]; ok {
+			continue
+		}
+		if input.matchCreated(time.Unix(entry.Created, 0), now) {
+			ids = append(ids, entry.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (d *DockerClient) cleanupNetworks(ctx context.Context, labels map[string]string, age time.Duration, now time.Time) ([]string, error) {
+	networks, err := d.ListNetworks(ctx, &NetworkInput{Labels: labels})
+	if err != nil {
+		return nil, err
+	}
+	input := &ClientInput{OlderThan: age}
+	ids := []string{}
+	for _, entry := range networks {
+		if input.matchCreated(entry.JSON.Created, now) {
+			ids = append(ids, entry.ID())
+		}
+	}
+	return ids, nil
+}
+
+func (d *DockerClient) cleanupVolumes(ctx context.Context, labels map[string]string, age time.Duration, now time.Time) ([]string, error) {
+	volumes, err := d.ListVolumes(ctx, &VolumeInput{Labels: labels})
+	if err != nil {
+		return nil, err
+	}
+	input := &ClientInput{OlderThan: age}
+	names := []string{}
+	for _, entry := range volumes {
+		created, err := time.Parse(time.RFC3339, entry.JSON.CreatedAt)
+		if age > 0 && err != nil {
+			// The age of the volume is unknown so it is left alone.
+			continue
+		}
+		if input.matchCreated(created, now) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// removeAll calls remove concurrently for each of the ids and returns, in
+// sorted order, the ids which were removed along with any errors.
+func removeAll(ids []string, remove func(string) error) ([]string, errset.ErrSet) {
+	removed := make(chan string)
+	errs := make(chan error)
+	for _, id := range ids {
+		go func(id string) {
+			if err := remove(id); err != nil {
+				errs <- err
+				return
+			}
+			removed <- id
+		}(id)
+	}
+
+	results := []string{}
+	errout := errset.ErrSet{}
+	for i := 0; i < len(ids); i++ {
+		select {
+		case err := <-errs:
+			errout = append(errout, err)
+		case id := <-removed:
+			results = append(results, id)
+		}
+	}
+	sort.Strings(results)
+	return results, errout
+}
@@ -0,0 +1,165 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type CleanupTest struct{}
+
+var _ = Suite(&CleanupTest{})
+
+func (s *CleanupTest) TestPolicyLabels(c *C) {
+	c.Assert((&CleanupPolicy{}).labels(), DeepEquals, map[string]string{"dockertest": "1"})
+	policy := NewCleanupPolicy()
+	policy.Session = "abc"
+	c.Assert(policy.labels(), DeepEquals, map[string]string{"dockertest": "1", SessionLabel: "abc"})
+	c.Assert(policy.Labels, DeepEquals, map[string]string{"dockertest": "1"})
+	policy = &CleanupPolicy{Labels: map[string]string{"ci": "1"}}
+	c.Assert(policy.labels(), DeepEquals, map[string]string{"ci": "1"})
+	policy = &CleanupPolicy{Session: "abc"}
+	c.Assert(policy.labels(), DeepEquals, map[string]string{SessionLabel: "abc"})
+}
+
+func (s *CleanupTest) TestCleanup(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	testing, err := dc.CreateNetwork(context.Background(), NewNetworkInput("testing"))
+	c.Assert(err, IsNil)
+	data, err := dc.CreateVolume(context.Background(), NewVolumeInput("data"))
+	c.Assert(err, IsNil)
+	input := NewClientInput(testImage)
+	input.Networks = []string{"testing"}
+	input.Mounts = []Mount{VolumeMount("data", "/data")}
+	labeled, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	// Resources without the dockertest label are left alone.
+	input = NewClientInput(testImage)
+	input.RemoveLabel("dockertest")
+	unlabeled, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	report, err := dc.Cleanup(context.Background(), NewCleanupPolicy())
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, &CleanupReport{
+		Containers: []string{labeled.ID()},
+		Networks:   []string{testing.ID()},
+		Volumes:    []string{data.Name()},
+	})
+
+	_, err = dc.ContainerInfo(context.Background(), labeled.ID())
+	c.Assert(err, Equals, ErrContainerNotFound)
+	_, err = dc.ContainerInfo(context.Background(), unlabeled.ID())
+	c.Assert(err, IsNil)
+	_, err = dc.NetworkInfo(context.Background(), testing.ID())
+	c.Assert(err, Equals, ErrNetworkNotFound)
+	_, err = dc.VolumeInfo(context.Background(), data.Name())
+	c.Assert(err, Equals, ErrVolumeNotFound)
+
+	report, err = dc.Cleanup(context.Background(), NewCleanupPolicy())
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, &CleanupReport{Containers: []string{}, Networks: []string{}, Volumes: []string{}})
+}
+
+func (s *CleanupTest) TestCleanupNilPolicy(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+
+	report, err := dc.Cleanup(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Containers, DeepEquals, []string{info.ID()})
+}
+
+func (s *CleanupTest) TestCleanupOlderThan(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	stale, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	c.Assert(server.SetCreated(stale.ID(), time.Now().Add(-time.Hour*2)), IsNil)
+	fresh, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	_, err = dc.CreateNetwork(context.Background(), NewNetworkInput("testing"))
+	c.Assert(err, IsNil)
+
+	policy := NewCleanupPolicy()
+	policy.OlderThan = time.Hour
+	report, err := dc.Cleanup(context.Background(), policy)
+	c.Assert(err, IsNil)
+	c.Assert(report.Containers, DeepEquals, []string{stale.ID()})
+	c.Assert(report.Networks, DeepEquals, []string{})
+	_, err = dc.ContainerInfo(context.Background(), fresh.ID())
+	c.Assert(err, IsNil)
+}
+
+func (s *CleanupTest) TestCleanupSession(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	first, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	// Containers from another session only differ by the session label.
+	input := NewClientInput(testImage)
+	input.ModifyConfig = func(config *container.Config, hostConfig *container.HostConfig) {
+		config.Labels[SessionLabel] = "other"
+	}
+	second, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	policy := NewCleanupPolicy()
+	policy.Session = dc.Session().ID
+	report, err := dc.Cleanup(context.Background(), policy)
+	c.Assert(err, IsNil)
+	c.Assert(report.Containers, DeepEquals, []string{first.ID()})
+	_, err = dc.ContainerInfo(context.Background(), second.ID())
+	c.Assert(err, IsNil)
+}
+
+// failingRemoveEngine fails to remove some containers.
+type failingRemoveEngine struct {
+	stubEngine
+	fail map[string]bool
+}
+
+func (e *failingRemoveEngine) ContainerRemove(ctx context.Context, id string, options types.ContainerRemoveOptions) error {
+	if e.fail[id] {
+		return errors.New("failed to remove " + id)
+	}
+	return nil
+}
+
+func (e *failingRemoveEngine) NetworkList(ctx context.Context, options types.NetworkListOptions) ([]types.NetworkResource, error) {
+	return nil, nil
+}
+
+func (e *failingRemoveEngine) VolumeList(ctx context.Context, filter filters.Args) (volume.VolumesListOKBody, error) {
+	return volume.VolumesListOKBody{}, nil
+}
+
+func (s *CleanupTest) TestCleanupErrors(c *C) {
+	engine := &failingRemoveEngine{
+		stubEngine: stubEngine{containers: []types.Container{{ID: "a"}, {ID: "b"}, {ID: "c"}}},
+		fail:       map[string]bool{"a": true, "c": true},
+	}
+	dc := NewClientWithEngine(engine)
+	report, err := dc.Cleanup(context.Background(), NewCleanupPolicy())
+	c.Assert(err, ErrorMatches, ".*failed to remove a.*")
+	c.Assert(err, ErrorMatches, ".*failed to remove c.*")
+	c.Assert(report.Containers, DeepEquals, []string{"b"})
+}
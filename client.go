@@ -3,9 +3,11 @@ package dockertest
 import (
 	"context"
 	"errors"
-	"io"
-	"io/ioutil"
+	"fmt"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/crewjam/errset"
 	"github.com/docker/docker/api/types"
@@ -15,15 +17,55 @@ import (
 	"github.com/docker/docker/client"
 )
 
+// cleanupTimeout bounds cleanup operations which run after the caller's
+// context has already been cancelled.
+const cleanupTimeout = time.Second * 30
+
 var (
 	// ErrContainerNotFound is returned by GetContainer if we were
 	// unable to find the requested c.
 	ErrContainerNotFound = errors.New("failed to locate the Container")
 )
 
+// RollbackError is returned by RunContainer if it failed after creating
+// the container and then could not remove it. It wraps Err, the original
+// failure, and errors.Is and errors.As also match RollbackErr.
+type RollbackError struct {
+	// ID is the id of the container which could not be removed.
+	ID string
+
+	Err         error
+	RollbackErr error
+}
+
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("%s (removing container %s also failed: %s)", e.Err, e.ID, e.RollbackErr)
+}
+
+// Unwrap returns the original failure.
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether RollbackErr matches target, the original failure is
+// matched through Unwrap.
+func (e *RollbackError) Is(target error) bool {
+	return errors.Is(e.RollbackErr, target)
+}
+
+// As finds the first error in RollbackErr's chain which matches target.
+func (e *RollbackError) As(target interface{}) bool {
+	return errors.As(e.RollbackErr, target)
+}
+
 // DockerClient provides a wrapper for the standard dc client.
 type DockerClient struct {
-	docker *client.Client
+	docker       Engine
+	host         string
+	timeout      time.Duration
+	dockerConfig string
+	tls          bool
+	session      *Session
 }
 
 // NewClient produces a new *DockerClient that can be used to interact
@@ -33,7 +75,48 @@ func NewClient() (*DockerClient, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &DockerClient{docker: docker}, nil
+	dc := NewClientWithEngine(docker)
+	// NewEnvClient enables TLS when DOCKER_CERT_PATH is set.
+	dc.tls = os.Getenv("DOCKER_CERT_PATH") != ""
+	return dc, nil
+}
+
+// NewClientWithEngine produces a new *DockerClient which uses the provided
+// Engine for all interactions with Docker.
+func NewClientWithEngine(engine Engine) *DockerClient {
+	dc := &DockerClient{docker: engine}
+	if hosted, ok := engine.(interface{ DaemonHost() string }); ok {
+		dc.host = hosted.DaemonHost()
+	}
+	dc.session = newSession(dc)
+	return dc
+}
+
+// Session returns the Session which identifies the containers, networks
+// and volumes created by this client.
+func (d *DockerClient) Session() *Session {
+	return d.session
+}
+
+// callContext returns a context for a single call to the engine. If the
+// client was configured with a timeout the returned context is bounded
+// by it.
+func (d *DockerClient) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.timeout > 0 {
+		return context.WithTimeout(ctx, d.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// cleanupContext returns a context which may be used to clean up after
+// a failure. Cleanup using an already cancelled context would fail
+// immediately so a new context, bounded by cleanupTimeout, is returned
+// in that case.
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx.Err() == nil {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(context.Background(), cleanupTimeout)
 }
 
 // ContainerInfo retrieves a single c by id and returns a *ContainerInfo
@@ -42,7 +125,9 @@ func (d *DockerClient) ContainerInfo(ctx context.Context, id string) (*Container
 	args := filters.NewArgs()
 	args.Add("id", id)
 	options := types.ContainerListOptions{Filters: args, All: true}
-	containers, err := d.docker.ContainerList(ctx, options)
+	listctx, cancel := d.callContext(ctx)
+	defer cancel()
+	containers, err := d.docker.ContainerList(listctx, options)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +136,9 @@ func (d *DockerClient) ContainerInfo(ctx context.Context, id string) (*Container
 		return nil, ErrContainerNotFound
 	}
 
-	inspection, err := d.docker.ContainerInspect(ctx, id)
+	inspectctx, cancel := d.callContext(ctx)
+	defer cancel()
+	inspection, err := d.docker.ContainerInspect(inspectctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -78,16 +165,26 @@ func (d *DockerClient) getContainerInfo(ctx context.Context, id string, containe
 func (d *DockerClient) ListContainers(ctx context.Context, input *ClientInput) ([]*ContainerInfo, error) {
 	options := types.ContainerListOptions{
 		All:     input.All,
-		Since:   input.Since,
-		Before:  input.Before,
 		Filters: input.FilterArgs(),
 	}
 
-	containers, err := d.docker.ContainerList(ctx, options)
+	listctx, cancel := d.callContext(ctx)
+	defer cancel()
+	listed, err := d.docker.ContainerList(listctx, options)
 	if err != nil {
 		return nil, err
 	}
 
+	// Docker has no filter for the age of a container so OlderThan and
+	// NewerThan are applied here.
+	now := time.Now()
+	containers := []types.Container{}
+	for _, entry := range listed {
+		if input.matchCreated(time.Unix(entry.Created, 0), now) {
+			containers = append(containers, entry)
+		}
+	}
+
 	infos := make(chan *ContainerInfo)
 	errs := make(chan error)
 
@@ -110,9 +207,12 @@ func (d *DockerClient) ListContainers(ctx context.Context, input *ClientInput) (
 }
 
 // RemoveContainer will delete the requested Container, force terminating
-// it if necessary.
+// it if necessary. Anonymous volumes used by the Container are removed
+// with it.
 func (d *DockerClient) RemoveContainer(ctx context.Context, id string) error {
-	err := d.docker.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true})
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	err := d.docker.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
 
 	// Docker's API does not expose their error structs and their
 	// IsErrNotFound does not seem to work.
@@ -131,39 +231,130 @@ func (d *DockerClient) RemoveContainer(ctx context.Context, id string) error {
 //    c := client.RunContainer("testimage", "testing", nil)
 //    port, err := c.Port(80)
 //    port.External
+//
+// The container is removed if RunContainer fails after creating it, a
+// *RollbackError is returned if it could not be.
 func (d *DockerClient) RunContainer(ctx context.Context, input *ClientInput) (*ContainerInfo, error) {
-	bindings, err := input.Ports.Bindings()
+	hostConfig, err := input.HostConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	for {
-		created, err := d.docker.ContainerCreate(
-			ctx,
-			input.ContainerConfig(),
-			&container.HostConfig{PortBindings: bindings},
-			&network.NetworkingConfig{}, "")
-		if err == nil {
-			err = d.docker.ContainerStart(ctx, created.ID, types.ContainerStartOptions{})
-			if err != nil {
-				return nil, err
-			}
+	// The built image is only used for this container, input is left
+	// unchanged so it can be used again.
+	image, policy := input.Image, input.PullPolicy
+	if input.Build != nil {
+		id, err := d.BuildImage(ctx, input.Build)
+		if err != nil {
+			return nil, err
+		}
+		image, policy = id, PullNever
+	}
+
+	config := input.ContainerConfig()
+	config.Image = image
+	config.Labels = d.session.apply(config.Labels)
+	for _, m := range hostConfig.Mounts {
+		if m.VolumeOptions != nil {
+			m.VolumeOptions.Labels = config.Labels
+		}
+	}
+	if input.ModifyConfig != nil {
+		input.ModifyConfig(config, hostConfig)
+	}
+
+	switch policy {
+	case "", PullIfNotPresent, PullNever:
+	case PullAlways:
+		if err := d.pull(ctx, config.Image, input.RegistryAuth, input.PullProgress); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidPullPolicy
+	}
 
-			info, err := d.ContainerInfo(ctx, created.ID)
-			info.Warnings = created.Warnings
-			return info, err
+	created, err := d.createContainer(ctx, config, hostConfig, input.NetworkingConfig())
+	if client.IsErrNotFound(err) && (policy == "" || policy == PullIfNotPresent) {
+		if err := d.pull(ctx, config.Image, input.RegistryAuth, input.PullProgress); err != nil {
+			return nil, err
 		}
+		created, err = d.createContainer(ctx, config, hostConfig, input.NetworkingConfig())
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		if client.IsErrNotFound(err) {
-			reader, err := d.docker.ImagePull(context.Background(), input.Image, types.ImagePullOptions{})
+	// Anything which fails from here on must remove the container, so a
+	// failed call does not leave it behind.
+	info, err := d.startContainer(ctx, input, created)
+	if err != nil {
+		return nil, d.rollback(ctx, created.ID, err)
+	}
+	return info, nil
+}
+
+// startContainer connects the created container to the remaining
+// Networks, copies Files into it and starts it.
+func (d *DockerClient) startContainer(ctx context.Context, input *ClientInput, created container.ContainerCreateCreatedBody) (*ContainerInfo, error) {
+	if len(input.Networks) > 1 {
+		for _, name := range input.Networks[1:] {
+			connectctx, cancel := d.callContext(ctx)
+			err := d.docker.NetworkConnect(connectctx, name, created.ID, input.endpointSettings())
+			cancel()
 			if err != nil {
 				return nil, err
 			}
-			io.Copy(ioutil.Discard, reader) // nolint: errcheck
-			continue
 		}
+	}
+
+	paths := []string{}
+	for path := range input.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := d.copyTo(ctx, created.ID, path, input.Files[path]); err != nil {
+			return nil, err
+		}
+	}
+
+	startctx, cancel := d.callContext(ctx)
+	err := d.docker.ContainerStart(startctx, created.ID, types.ContainerStartOptions{})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	if input.StartupPeriod > 0 {
+		if err := d.waitForStartup(ctx, created.ID, input.StartupPeriod); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := d.ContainerInfo(ctx, created.ID)
+	if err != nil {
 		return nil, err
 	}
+	info.Warnings = created.Warnings
+	return info, nil
+}
+
+// rollback removes the container which RunContainer created before
+// failing with err. The removal runs even if ctx has been cancelled.
+func (d *DockerClient) rollback(ctx context.Context, id string, err error) error {
+	ctx, cancel := cleanupContext(ctx)
+	defer cancel()
+	if removeErr := d.RemoveContainer(ctx, id); removeErr != nil {
+		return &RollbackError{Err: err, RollbackErr: removeErr, ID: id}
+	}
+	return err
+}
+
+// createContainer creates, but does not start, a new container.
+func (d *DockerClient) createContainer(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig) (container.ContainerCreateCreatedBody, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	return d.docker.ContainerCreate(ctx, config, hostConfig, networkingConfig, "")
 }
 
 // Service will return a *Service struct that may be used to spin up
@@ -2,10 +2,13 @@ package dockertest
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
 )
 
 // ClientInput is used to provide inputs to the RunContainer function.
@@ -15,12 +18,134 @@ type ClientInput struct {
 	Labels      map[string]string
 	Environment []string
 
-	// Fields provided for the purposes of filtering containers.
+	// Command replaces the image's CMD when set.
+	Command []string
+
+	// Entrypoint replaces the image's ENTRYPOINT when set. Docker does
+	// not use the image's CMD if Entrypoint is set and []string{""}
+	// removes the image's ENTRYPOINT.
+	Entrypoint []string
+
+	// User, WorkingDir and Hostname replace the image's defaults when
+	// set. Docker uses the first 12 characters of the container's id as
+	// the hostname if one is not provided.
+	User       string
+	WorkingDir string
+	Hostname   string
+
+	// Healthcheck, if set, replaces the image's HEALTHCHECK.
+	Healthcheck *Healthcheck
+
+	// StartupPeriod, if set, causes RunContainer to wait this long after
+	// starting the container. If the container exits in that time it is
+	// removed and a *StartupError is returned. Containers which are
+	// expected to exit, such as one-shot commands, should leave it unset.
+	StartupPeriod time.Duration
+
+	// Memory limits the container's memory in bytes and MemorySwap
+	// limits memory plus swap, -1 allows unlimited swap. Memory must be
+	// set to use MemorySwap.
+	Memory     int64
+	MemorySwap int64
+
+	// CPUShares sets the container's relative CPU weight. CPUQuota
+	// limits the CPU time, in microseconds, the container may use in
+	// each CPUPeriod.
+	CPUShares int64
+	CPUQuota  int64
+	CPUPeriod int64
+
+	// PidsLimit limits the number of processes in the container, -1
+	// means unlimited.
+	PidsLimit int64
+
+	Ulimits []Ulimit
+
+	// Sysctls sets namespaced kernel parameters, such as
+	// net.core.somaxconn, for the container.
+	Sysctls map[string]string
+
+	// CapAdd and CapDrop add and remove Linux capabilities, such as
+	// NET_ADMIN or ALL.
+	CapAdd  []string
+	CapDrop []string
+
+	// ReadOnlyRootfs mounts the container's root filesystem read only,
+	// Mounts remain writable unless they are also marked read only.
+	ReadOnlyRootfs bool
+	Privileged     bool
+
+	// SecurityOpt configures labeling, apparmor, seccomp and
+	// no-new-privileges, for example "seccomp=unconfined".
+	SecurityOpt []string
+
+	// ModifyConfig, if set, is called with the configuration built from
+	// the other fields just before the container is created. It may be
+	// used to set anything which ClientInput does not provide a field for.
+	ModifyConfig func(config *container.Config, hostConfig *container.HostConfig)
+
+	// PullPolicy controls when Image is pulled, PullIfNotPresent
+	// is used if not set.
+	PullPolicy PullPolicy
+
+	// PullProgress, if set, receives progress messages while Image
+	// is being pulled.
+	PullProgress Progress
+
+	// RegistryAuth provides explicit credentials used to pull Image. If
+	// not set credentials are resolved from the Docker config.
+	RegistryAuth *types.AuthConfig
+
+	// Build, if set, causes RunContainer to build an image before
+	// creating the container. The container uses the built image, which
+	// is never pulled, in place of Image. Image itself is not modified.
+	// A Build which uses a Context stream may only be run once.
+	Build *BuildInput
+
+	// Files maps directories inside of the container to content which
+	// RunContainer copies into them after the container is created but
+	// before it is started. Each directory must exist in the image.
+	Files map[string]CopySource
+
+	// Networks lists the networks, by name or id, the container is
+	// connected to. The container is created on the first network and
+	// connected to the others before it is started. Docker's default
+	// bridge network is used if not set.
+	Networks []string
+
+	// Aliases are the names other containers may use to reach this
+	// container on each of the user-defined networks in Networks.
+	Aliases []string
+
+	// Mounts are filesystems mounted into the container. Volumes which
+	// Docker creates for the container are given the container's labels.
+	Mounts []Mount
+
+	// Fields provided for the purposes of filtering containers. Since
+	// and Before are container ids or names. OlderThan and NewerThan are
+	// compared against the time each container was created.
 	Since     string
 	Before    string
 	Status    string
 	All       bool
 	OlderThan time.Duration
+	NewerThan time.Duration
+
+	// Name and Network match containers by name and by the networks
+	// they are connected to. Name is not used when creating containers.
+	Name    string
+	Network string
+
+	// ExitCodes matches exited containers with any of the exit codes.
+	ExitCodes []int
+
+	// Health matches containers by health status, one of starting,
+	// healthy, unhealthy or none.
+	Health string
+
+	// LabelExists matches containers which have each of the labels,
+	// regardless of the value.
+	LabelExists []string
 }
 
 // SetLabel will add set the provided label key to the provided value.
@@ -37,10 +162,63 @@ func (i *ClientInput) RemoveLabel(key string) {
 // be passed to the ContainerCreate() API call.
 func (i *ClientInput) ContainerConfig() *container.Config {
 	return &container.Config{
-		Image:  i.Image,
-		Labels: i.Labels,
-		Env:    i.Environment,
+		Image:       i.Image,
+		Labels:      i.Labels,
+		Env:         i.Environment,
+		Cmd:         i.Command,
+		Entrypoint:  i.Entrypoint,
+		User:        i.User,
+		WorkingDir:  i.WorkingDir,
+		Hostname:    i.Hostname,
+		Healthcheck: i.Healthcheck.HealthConfig(),
+	}
+}
+
+// HostConfig will return a *container.HostConfig struct which may be
+// passed to the ContainerCreate() API call.
+func (i *ClientInput) HostConfig() (*container.HostConfig, error) {
+	bindings, err := i.Ports.Bindings()
+	if err != nil {
+		return nil, err
+	}
+	if err := i.validateHostOptions(); err != nil {
+		return nil, err
+	}
+	if err := i.Healthcheck.validate(); err != nil {
+		return nil, err
+	}
+	config := &container.HostConfig{PortBindings: bindings}
+	i.applyHostOptions(config)
+	if len(i.Networks) > 0 {
+		config.NetworkMode = container.NetworkMode(i.Networks[0])
+	}
+	for _, m := range i.Mounts {
+		converted, err := m.mount(i.Labels)
+		if err != nil {
+			return nil, err
+		}
+		config.Mounts = append(config.Mounts, converted)
+	}
+	return config, nil
+}
+
+// NetworkingConfig will return a *network.NetworkingConfig struct which
+// may be passed to the ContainerCreate() API call. Only the first of
+// Networks is included, Docker does not allow more than one at create.
+func (i *ClientInput) NetworkingConfig() *network.NetworkingConfig {
+	config := &network.NetworkingConfig{}
+	if len(i.Networks) > 0 {
+		config.EndpointsConfig = map[string]*network.EndpointSettings{
+			i.Networks[0]: i.endpointSettings(),
+		}
 	}
+	return config
+}
+
+// endpointSettings returns the settings used when connecting the
+// container to each of Networks.
+func (i *ClientInput) endpointSettings() *network.EndpointSettings {
+	return &network.EndpointSettings{Aliases: i.Aliases}
 }
 
 // AddEnvironmentVar adds an environment variable.
@@ -66,9 +244,50 @@ func (i *ClientInput) FilterArgs() filters.Args {
 		args.Add("status", i.Status)
 	}
 
+	if i.Since != "" {
+		args.Add("since", i.Since)
+	}
+
+	if i.Before != "" {
+		args.Add("before", i.Before)
+	}
+
+	if i.Name != "" {
+		args.Add("name", i.Name)
+	}
+
+	if i.Network != "" {
+		args.Add("network", i.Network)
+	}
+
+	for _, code := range i.ExitCodes {
+		args.Add("exited", strconv.Itoa(code))
+	}
+
+	if i.Health != "" {
+		args.Add("health", i.Health)
+	}
+
+	for _, key := range i.LabelExists {
+		args.Add("label", key)
+	}
+
 	return args
 }
 
+// matchCreated returns true if a container created at the provided time
+// satisfies OlderThan and NewerThan.
+func (i *ClientInput) matchCreated(created time.Time, now time.Time) bool {
+	age := now.Sub(created)
+	if i.OlderThan > 0 && age < i.OlderThan {
+		return false
+	}
+	if i.NewerThan > 0 && age > i.NewerThan {
+		return false
+	}
+	return true
+}
+
 // NewClientInput produces a *ClientInput struct.
 func NewClientInput(image string) *ClientInput {
 	input := &ClientInput{
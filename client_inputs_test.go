@@ -1,8 +1,14 @@
 package dockertest
 
 import (
+	"context"
+	"time"
+
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/opalmer/dockertest/fakeengine"
 	. "gopkg.in/check.v1"
 )
 
@@ -44,6 +50,89 @@ func (s *ClientInputsTest) TestContainerConfig(c *C) {
 	})
 }
 
+func (s *ClientInputsTest) TestContainerConfigOverrides(c *C) {
+	input := NewClientInput("test")
+	input.Command = []string{"-c", "config"}
+	input.Entrypoint = []string{"/bin/server"}
+	input.User = "nobody"
+	input.WorkingDir = "/srv"
+	input.Hostname = "server"
+	input.Healthcheck = NewHealthcheck("true")
+	config := input.ContainerConfig()
+	c.Assert(config.Cmd, DeepEquals, strslice.StrSlice{"-c", "config"})
+	c.Assert(config.Entrypoint, DeepEquals, strslice.StrSlice{"/bin/server"})
+	c.Assert(config.User, Equals, "nobody")
+	c.Assert(config.WorkingDir, Equals, "/srv")
+	c.Assert(config.Hostname, Equals, "server")
+	c.Assert(config.Healthcheck.Test, DeepEquals, []string{"CMD", "true"})
+}
+
+func (s *ClientInputsTest) TestRunContainerConfig(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{Config: container.Config{
+		Entrypoint: []string{"/docker-entrypoint.sh"},
+		Cmd:        []string{"nginx"},
+		User:       "root",
+	}})
+
+	input := NewClientInput(testImage)
+	input.Command = []string{"nginx", "-g", "daemon off;"}
+	input.User = "nobody"
+	input.WorkingDir = "/srv"
+	input.ModifyConfig = func(config *container.Config, hostConfig *container.HostConfig) {
+		config.StopSignal = "SIGQUIT"
+		hostConfig.ExtraHosts = []string{"db:10.0.0.1"}
+	}
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(info.JSON.Config.Entrypoint, DeepEquals, strslice.StrSlice{"/docker-entrypoint.sh"})
+	c.Assert(info.JSON.Config.Cmd, DeepEquals, strslice.StrSlice{"nginx", "-g", "daemon off;"})
+	c.Assert(info.JSON.Config.User, Equals, "nobody")
+	c.Assert(info.JSON.Config.WorkingDir, Equals, "/srv")
+	c.Assert(info.JSON.Config.Hostname, Equals, info.ID()[:12])
+	c.Assert(info.JSON.Config.StopSignal, Equals, "SIGQUIT")
+	c.Assert(info.JSON.HostConfig.ExtraHosts, DeepEquals, []string{"db:10.0.0.1"})
+
+	// Replacing the entrypoint means the image's command is not used.
+	input = NewClientInput(testImage)
+	input.Entrypoint = []string{"/bin/sh"}
+	input.Hostname = "server"
+	info, err = dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(info.JSON.Config.Entrypoint, DeepEquals, strslice.StrSlice{"/bin/sh"})
+	c.Assert(info.JSON.Config.Cmd, HasLen, 0)
+	c.Assert(info.JSON.Config.Hostname, Equals, "server")
+}
+
+func (s *ClientInputsTest) TestHostConfig(c *C) {
+	input := NewClientInput("test")
+	input.Networks = []string{"testing"}
+	input.Mounts = []Mount{TmpfsMount("/data", 0)}
+	config, err := input.HostConfig()
+	c.Assert(err, IsNil)
+	c.Assert(config.NetworkMode, Equals, container.NetworkMode("testing"))
+	c.Assert(config.Mounts, HasLen, 1)
+	c.Assert(config.Mounts[0].Target, Equals, "/data")
+
+	input.Mounts = []Mount{TmpfsMount("data", 0)}
+	_, err = input.HostConfig()
+	c.Assert(err, Equals, ErrMountTargetNotProvided)
+}
+
+func (s *ClientInputsTest) TestNetworkingConfig(c *C) {
+	input := NewClientInput("test")
+	c.Assert(input.NetworkingConfig(), DeepEquals, &network.NetworkingConfig{})
+
+	input.Networks = []string{"first", "second"}
+	input.Aliases = []string{"db"}
+	c.Assert(input.NetworkingConfig(), DeepEquals, &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			"first": {Aliases: []string{"db"}},
+		},
+	})
+}
+
 func (s *ClientInputsTest) TestFilterArgs(c *C) {
 	input := NewClientInput("test")
 	input.SetLabel("foo", "bar")
@@ -58,6 +147,44 @@ func (s *ClientInputsTest) TestFilterArgs(c *C) {
 	c.Assert(input.FilterArgs(), DeepEquals, expected)
 }
 
+func (s *ClientInputsTest) TestFilterArgsContainerFields(c *C) {
+	input := &ClientInput{
+		Since:       "a",
+		Before:      "b",
+		Name:        "name",
+		Network:     "testing",
+		ExitCodes:   []int{0, 1},
+		Health:      "healthy",
+		LabelExists: []string{"dockertest"},
+	}
+
+	expected := filters.NewArgs()
+	expected.Add("since", "a")
+	expected.Add("before", "b")
+	expected.Add("name", "name")
+	expected.Add("network", "testing")
+	expected.Add("exited", "0")
+	expected.Add("exited", "1")
+	expected.Add("health", "healthy")
+	expected.Add("label", "dockertest")
+
+	c.Assert(input.FilterArgs(), DeepEquals, expected)
+}
+
+func (s *ClientInputsTest) TestMatchCreated(c *C) {
+	now := time.Now()
+	input := &ClientInput{}
+	c.Assert(input.matchCreated(now, now), Equals, true)
+
+	input.OlderThan = time.Hour
+	c.Assert(input.matchCreated(now.Add(-time.Minute), now), Equals, false)
+	c.Assert(input.matchCreated(now.Add(-time.Hour*2), now), Equals, true)
+
+	input.NewerThan = time.Hour * 3
+	c.Assert(input.matchCreated(now.Add(-time.Hour*2), now), Equals, true)
+	c.Assert(input.matchCreated(now.Add(-time.Hour*4), now), Equals, false)
+}
+
 func (s *ClientInputsTest) TestNewClientInput(c *C) {
 	input := NewClientInput("test")
 	c.Assert(input, DeepEquals, &ClientInput{
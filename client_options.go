@@ -0,0 +1,174 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/sockets"
+	"github.com/docker/go-connections/tlsconfig"
+)
+
+var (
+	// ErrConflictingOptions is returned by NewClientWithOptions if two of
+	// the provided options cannot be used together.
+	ErrConflictingOptions = errors.New("conflicting client options provided")
+)
+
+// clientOptions holds the settings which a ClientOption may modify.
+type clientOptions struct {
+	host       string
+	version    string
+	pinned     bool
+	negotiate  bool
+	httpClient *http.Client
+	tls        *tlsconfig.Options
+	timeout    time.Duration
+	config     string
+}
+
+// ClientOption is used to configure the *DockerClient produced by
+// NewClientWithOptions.
+type ClientOption func(*clientOptions) error
+
+// WithHost sets the url of the Docker daemon, for example
+// tcp://127.0.0.1:2376 or unix:///var/run/docker.sock.
+func WithHost(host string) ClientOption {
+	return func(options *clientOptions) error {
+		if _, err := client.ParseHostURL(host); err != nil {
+			return err
+		}
+		options.host = host
+		return nil
+	}
+}
+
+// WithTLS enables TLS using the provided CA, certificate and key files.
+// The certificate and key may be empty if the daemon does not require
+// client authentication. The CA may be empty to use the system pool.
+func WithTLS(ca string, cert string, key string) ClientOption {
+	return func(options *clientOptions) error {
+		options.tls = &tlsconfig.Options{CAFile: ca, CertFile: cert, KeyFile: key}
+		return nil
+	}
+}
+
+// WithAPIVersion pins the API version used to talk to the daemon.
+func WithAPIVersion(version string) ClientOption {
+	return func(options *clientOptions) error {
+		options.version = version
+		options.pinned = true
+		return nil
+	}
+}
+
+// WithAPIVersionNegotiation causes the client to ask the daemon which
+// API version it supports and to downgrade if required.
+func WithAPIVersionNegotiation() ClientOption {
+	return func(options *clientOptions) error {
+		options.negotiate = true
+		return nil
+	}
+}
+
+// WithHTTPClient sets the *http.Client used to talk to the daemon. This
+// cannot be combined with WithTLS, configure TLS on the provided client
+// instead.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(options *clientOptions) error {
+		options.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithTimeout bounds every individual call made to the daemon by the
+// provided duration.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(options *clientOptions) error {
+		options.timeout = timeout
+		return nil
+	}
+}
+
+// WithDockerConfig sets the directory containing the config.json used
+// to resolve registry credentials. By default $DOCKER_CONFIG or ~/.docker
+// is used.
+func WithDockerConfig(dir string) ClientOption {
+	return func(options *clientOptions) error {
+		options.config = dir
+		return nil
+	}
+}
+
+// client returns the *http.Client to use for the provided options, or
+// nil if the docker client's default should be used.
+func (o *clientOptions) client() (*http.Client, error) {
+	if o.tls == nil {
+		return o.httpClient, nil
+	}
+	if o.httpClient != nil {
+		return nil, ErrConflictingOptions
+	}
+
+	config, err := tlsconfig.Client(*o.tls)
+	if err != nil {
+		return nil, err
+	}
+	hostURL, err := client.ParseHostURL(o.host)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{TLSClientConfig: config}
+	if err := sockets.ConfigureTransport(transport, hostURL.Scheme, hostURL.Host); err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, CheckRedirect: client.CheckRedirect}, nil
+}
+
+// NewClientWithOptions produces a new *DockerClient configured by the
+// provided options. Unlike NewClient, environment variables such as
+// DOCKER_HOST are not consulted; the default is to talk to the local
+// daemon using the latest API version.
+func NewClientWithOptions(options ...ClientOption) (*DockerClient, error) {
+	settings := &clientOptions{
+		host:    client.DefaultDockerHost,
+		version: api.DefaultVersion,
+	}
+	for _, option := range options {
+		if err := option(settings); err != nil {
+			return nil, err
+		}
+	}
+	if settings.pinned && settings.negotiate {
+		return nil, ErrConflictingOptions
+	}
+
+	httpClient, err := settings.client()
+	if err != nil {
+		return nil, err
+	}
+
+	docker, err := client.NewClient(settings.host, settings.version, httpClient, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.negotiate {
+		ctx := context.Background()
+		if settings.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, settings.timeout)
+			defer cancel()
+		}
+		docker.NegotiateAPIVersion(ctx)
+	}
+
+	dc := NewClientWithEngine(docker)
+	dc.timeout = settings.timeout
+	dc.dockerConfig = settings.config
+	dc.tls = settings.tls != nil
+	return dc, nil
+}
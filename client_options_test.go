@@ -0,0 +1,126 @@
+package dockertest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type ClientOptionsTest struct{}
+
+var _ = Suite(&ClientOptionsTest{})
+
+func (s *ClientOptionsTest) TestWithHost(c *C) {
+	first, err := fakeengine.NewServer()
+	c.Assert(err, IsNil)
+	defer first.Close() // nolint: errcheck
+	second, err := fakeengine.NewServer()
+	c.Assert(err, IsNil)
+	defer second.Close() // nolint: errcheck
+	first.AddImage(testImage, &fakeengine.Image{})
+	second.AddImage(testImage, &fakeengine.Image{})
+
+	a, err := NewClientWithOptions(WithHost(first.Host()))
+	c.Assert(err, IsNil)
+	b, err := NewClientWithOptions(WithHost(second.Host()))
+	c.Assert(err, IsNil)
+
+	info, err := a.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	_, err = b.ContainerInfo(context.Background(), info.ID())
+	c.Assert(err, Equals, ErrContainerNotFound)
+	_, err = a.ContainerInfo(context.Background(), info.ID())
+	c.Assert(err, IsNil)
+}
+
+func (s *ClientOptionsTest) TestWithHostInvalid(c *C) {
+	_, err := NewClientWithOptions(WithHost("/////"))
+	c.Assert(err, ErrorMatches, "unable to parse docker host `/////`")
+}
+
+func (s *ClientOptionsTest) TestWithAPIVersion(c *C) {
+	dc, err := NewClientWithOptions(WithAPIVersion("1.25"))
+	c.Assert(err, IsNil)
+	c.Assert(dc.docker.(*client.Client).ClientVersion(), Equals, "1.25")
+}
+
+func (s *ClientOptionsTest) TestWithAPIVersionNegotiation(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.26")
+	}))
+	defer server.Close()
+
+	dc, err := NewClientWithOptions(
+		WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")),
+		WithAPIVersionNegotiation())
+	c.Assert(err, IsNil)
+	c.Assert(dc.docker.(*client.Client).ClientVersion(), Equals, "1.26")
+}
+
+func (s *ClientOptionsTest) TestConflictingOptions(c *C) {
+	_, err := NewClientWithOptions(WithAPIVersion("1.25"), WithAPIVersionNegotiation())
+	c.Assert(err, Equals, ErrConflictingOptions)
+	_, err = NewClientWithOptions(WithHTTPClient(&http.Client{}), WithTLS("", "", ""))
+	c.Assert(err, Equals, ErrConflictingOptions)
+}
+
+func (s *ClientOptionsTest) TestWithTLSMissingFiles(c *C) {
+	_, err := NewClientWithOptions(WithTLS("/missing/ca.pem", "", ""))
+	c.Assert(err, ErrorMatches, ".*/missing/ca.pem.*")
+}
+
+func (s *ClientOptionsTest) TestWithHTTPClient(c *C) {
+	server, err := fakeengine.NewServer()
+	c.Assert(err, IsNil)
+	defer server.Close() // nolint: errcheck
+
+	requests := 0
+	httpClient := &http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	dc, err := NewClientWithOptions(WithHost(server.Host()), WithHTTPClient(httpClient))
+	c.Assert(err, IsNil)
+	_, err = dc.ListContainers(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	c.Assert(requests, Equals, 1)
+}
+
+func (s *ClientOptionsTest) TestWithTimeout(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 200)
+	}))
+	defer server.Close()
+
+	dc, err := NewClientWithOptions(
+		WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")),
+		WithTimeout(time.Millisecond*10))
+	c.Assert(err, IsNil)
+	_, err = dc.ListContainers(context.Background(), &ClientInput{})
+	c.Assert(err, ErrorMatches, ".*context deadline exceeded.*")
+}
+
+func (s *ClientOptionsTest) TestAddressUsesHost(c *C) {
+	dc, err := NewClientWithOptions(WithHost("tcp://1.2.3.4:2376"))
+	c.Assert(err, IsNil)
+	info := &ContainerInfo{
+		client: dc,
+		Data:   types.Container{Ports: []types.Port{{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080}}},
+	}
+	port, err := info.Port(80)
+	c.Assert(err, IsNil)
+	c.Assert(port.Address, Equals, "1.2.3.4")
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/opalmer/dockertest/fakeengine"
 	. "gopkg.in/check.v1"
 )
 
@@ -167,3 +169,153 @@ func (s *ClientTest) TestService(c *C) {
 	svc := dc.Service(input)
 	c.Assert(svc.Input, DeepEquals, input)
 }
+
+func (s *ClientTest) TestNewClientDockerHost(c *C) {
+	server, err := fakeengine.NewServer()
+	c.Assert(err, IsNil)
+	defer server.Close() // nolint: errcheck
+
+	dockerhost, set := os.LookupEnv("DOCKER_HOST")
+	if set {
+		defer os.Setenv("DOCKER_HOST", dockerhost) // nolint: errcheck
+	} else {
+		defer os.Unsetenv("DOCKER_HOST") // nolint: errcheck
+	}
+	c.Assert(os.Setenv("DOCKER_HOST", server.Host()), IsNil)
+
+	server.AddImage(testImage, &fakeengine.Image{})
+	dc, err := NewClient()
+	c.Assert(err, IsNil)
+	info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	c.Assert(info.State.Running, Equals, true)
+}
+
+func (s *ClientTest) TestRunContainerFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddRemoteImage(testImage, &fakeengine.Image{})
+
+	input := NewClientInput(testImage)
+	input.Ports.Add(&Port{Private: 80, Public: RandomPort, Protocol: ProtocolTCP})
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(server.HasImage(testImage), Equals, true)
+	c.Assert(info.HasLabel("dockertest", "1"), Equals, true)
+
+	port, err := info.Port(80)
+	c.Assert(err, IsNil)
+	c.Assert(port.Public, Not(Equals), RandomPort)
+
+	c.Assert(dc.RemoveContainer(context.Background(), info.ID()), IsNil)
+	_, err = dc.ContainerInfo(context.Background(), info.ID())
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
+
+func (s *ClientTest) TestRunContainerPullFailsFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+
+	_, err := dc.RunContainer(context.Background(), NewClientInput("missing"))
+	c.Assert(err, ErrorMatches, ".*repository does not exist.*")
+}
+
+func (s *ClientTest) TestRunContainerStartFailsFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{StartError: "failed to start"})
+
+	_, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, ErrorMatches, ".*failed to start")
+}
+
+func (s *ClientTest) TestListContainersFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	ids := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		input := NewClientInput(testImage)
+		input.SetLabel("group", "a")
+		info, err := dc.RunContainer(context.Background(), input)
+		c.Assert(err, IsNil)
+		ids[info.ID()] = true
+	}
+	_, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+
+	input := NewClientInput(testImage)
+	input.SetLabel("group", "a")
+	containers, err := dc.ListContainers(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(containers, HasLen, 3)
+	for _, entry := range containers {
+		c.Assert(ids[entry.ID()], Equals, true)
+	}
+}
+
+func (s *ClientTest) TestListContainersAgeFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	stale, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	c.Assert(server.SetCreated(stale.ID(), time.Now().Add(-time.Hour*2)), IsNil)
+	fresh, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+
+	input := NewClientInput(testImage)
+	input.OlderThan = time.Hour
+	containers, err := dc.ListContainers(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(containers, HasLen, 1)
+	c.Assert(containers[0].ID(), Equals, stale.ID())
+
+	input = NewClientInput(testImage)
+	input.NewerThan = time.Hour
+	containers, err = dc.ListContainers(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(containers, HasLen, 1)
+	c.Assert(containers[0].ID(), Equals, fresh.ID())
+}
+
+func (s *ClientTest) TestListContainersFiltersFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	ids := []string{}
+	for i := 0; i < 3; i++ {
+		info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+		c.Assert(err, IsNil)
+		ids = append(ids, info.ID())
+	}
+	c.Assert(server.Exit(ids[2], 4), IsNil)
+
+	list := func(modify func(*ClientInput)) []string {
+		input := NewClientInput(testImage)
+		input.All = true
+		modify(input)
+		containers, err := dc.ListContainers(context.Background(), input)
+		c.Assert(err, IsNil)
+		found := []string{}
+		for _, entry := range containers {
+			found = append(found, entry.ID())
+		}
+		sort.Strings(found)
+		return found
+	}
+	sorted := func(values ...string) []string {
+		sort.Strings(values)
+		return values
+	}
+
+	c.Assert(list(func(i *ClientInput) { i.Since = ids[0] }), DeepEquals, sorted(ids[1], ids[2]))
+	c.Assert(list(func(i *ClientInput) { i.Before = ids[1] }), DeepEquals, sorted(ids[0]))
+	c.Assert(list(func(i *ClientInput) { i.ExitCodes = []int{4} }), DeepEquals, sorted(ids[2]))
+	c.Assert(list(func(i *ClientInput) { i.Network = "bridge" }), DeepEquals, sorted(ids...))
+	c.Assert(list(func(i *ClientInput) { i.Health = "healthy" }), DeepEquals, []string{})
+	c.Assert(list(func(i *ClientInput) { i.LabelExists = []string{"missing"} }), DeepEquals, []string{})
+}
@@ -26,6 +26,10 @@ var (
 	// ErrContainerStillRunning is returned by Finished() if the Container
 	// is still running.
 	ErrContainerStillRunning = errors.New("container still running")
+
+	// ErrNetworkNotConnected is returned by IPAddress() if the Container
+	// is not connected to the requested network.
+	ErrNetworkNotConnected = errors.New("container is not connected to the network")
 )
 
 // ContainerInfo provides a wrapper around information.
@@ -57,7 +61,13 @@ func (c *ContainerInfo) HasLabel(name string, value string) bool {
 
 // Refresh will refresh the data present on this struct.
 func (c *ContainerInfo) Refresh() error {
-	updated, err := c.client.ContainerInfo(context.Background(), c.ID())
+	return c.RefreshContext(context.Background())
+}
+
+// RefreshContext will refresh the data present on this struct using the
+// provided context.
+func (c *ContainerInfo) RefreshContext(ctx context.Context) error {
+	updated, err := c.client.ContainerInfo(ctx, c.ID())
 	if err != nil {
 		return err
 	}
@@ -85,6 +95,18 @@ func (c *ContainerInfo) address(ip string) (string, error) {
 		}
 	}
 
+	// If the client was configured to talk to a remote daemon then
+	// ports will be published on that host.
+	if c.client != nil && strings.HasPrefix(c.client.host, "tcp://") {
+		parsed, err := url.Parse(c.client.host)
+		if err != nil {
+			return "", err
+		}
+		if host := parsed.Hostname(); host != "" {
+			return host, nil
+		}
+	}
+
 	// In the majority of cases 127.0.0.1 will be a safe bet if
 	// DOCKER_URL is not set. We could try connecting to the port
 	// but we don't know if the socket is listening yet and we also
@@ -94,8 +116,8 @@ func (c *ContainerInfo) address(ip string) (string, error) {
 
 // Port will return types.Port for the requested internal port. Note, attempts
 // will be made to correct the address before returning. If $DOCKER_URL is not
-// set however 127.0.0.1 will be returned if a specific IP was not provided by
-// Docker.
+// set and the client is not talking to a tcp:// host however 127.0.0.1 will be
+// returned if a specific IP was not provided by Docker.
 func (c *ContainerInfo) Port(internal int) (*Port, error) {
 	for _, port := range c.Data.Ports {
 		if port.PrivatePort == uint16(internal) {
@@ -124,6 +146,59 @@ func (c *ContainerInfo) ID() string {
 	return c.Data.ID
 }
 
+// ExitCode returns the exit code of the Container as of the last
+// refresh. It is zero if the Container has not exited.
+func (c *ContainerInfo) ExitCode() int {
+	if c.State == nil {
+		return 0
+	}
+	return c.State.ExitCode
+}
+
+// OOMKilled returns true if the Container was killed because it ran
+// out of memory as of the last refresh.
+func (c *ContainerInfo) OOMKilled() bool {
+	return c.State != nil && c.State.OOMKilled
+}
+
+// ErrorMessage returns the error Docker recorded for the Container as of
+// the last refresh, such as a failure to start, or "" if there was none.
+func (c *ContainerInfo) ErrorMessage() string {
+	if c.State == nil {
+		return ""
+	}
+	return c.State.Error
+}
+
+// IPAddresses returns the Container's IP address on each network it is
+// connected to, keyed by network name, as of the last refresh. Networks
+// which do not assign addresses, such as host, map to "".
+func (c *ContainerInfo) IPAddresses() map[string]string {
+	addresses := map[string]string{}
+	if c.JSON.NetworkSettings == nil {
+		return addresses
+	}
+	for name, settings := range c.JSON.NetworkSettings.Networks {
+		if settings != nil {
+			addresses[name] = settings.IPAddress
+		}
+	}
+	return addresses
+}
+
+// IPAddress returns the Container's IP address on the network with the
+// given name or id as of the last refresh.
+func (c *ContainerInfo) IPAddress(network string) (string, error) {
+	if c.JSON.NetworkSettings != nil {
+		for name, settings := range c.JSON.NetworkSettings.Networks {
+			if settings != nil && (name == network || settings.NetworkID == network) {
+				return settings.IPAddress, nil
+			}
+		}
+	}
+	return "", ErrNetworkNotConnected
+}
+
 // Started returns the time the Container was started at.
 func (c *ContainerInfo) Started() (time.Time, error) {
 	if c.State.StartedAt == timeNotSet {
@@ -1,10 +1,13 @@
 package dockertest
 
 import (
+	"context"
 	"os"
 	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/opalmer/dockertest/fakeengine"
 	. "gopkg.in/check.v1"
 )
 
@@ -61,6 +64,52 @@ func (s *ContainerInfoTest) TestID(c *C) {
 	c.Assert(info.ID(), Equals, "foobar")
 }
 
+func (s *ContainerInfoTest) TestExitStatus(c *C) {
+	info := &ContainerInfo{}
+	c.Assert(info.ExitCode(), Equals, 0)
+	c.Assert(info.OOMKilled(), Equals, false)
+	c.Assert(info.ErrorMessage(), Equals, "")
+
+	info = &ContainerInfo{
+		State: &types.ContainerState{
+			ExitCode:  137,
+			OOMKilled: true,
+			Error:     "error",
+		},
+	}
+	c.Assert(info.ExitCode(), Equals, 137)
+	c.Assert(info.OOMKilled(), Equals, true)
+	c.Assert(info.ErrorMessage(), Equals, "error")
+}
+
+func (s *ContainerInfoTest) TestIPAddress(c *C) {
+	info := &ContainerInfo{}
+	c.Assert(info.IPAddresses(), DeepEquals, map[string]string{})
+	_, err := info.IPAddress("bridge")
+	c.Assert(err, Equals, ErrNetworkNotConnected)
+
+	info = &ContainerInfo{JSON: types.ContainerJSON{
+		NetworkSettings: &types.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"bridge":  {NetworkID: "a", IPAddress: "172.17.0.2"},
+				"testing": {NetworkID: "b", IPAddress: "172.18.0.2"},
+				"host":    {NetworkID: "c"},
+			},
+		},
+	}}
+	c.Assert(info.IPAddresses(), DeepEquals, map[string]string{
+		"bridge": "172.17.0.2", "testing": "172.18.0.2", "host": "",
+	})
+	address, err := info.IPAddress("testing")
+	c.Assert(err, IsNil)
+	c.Assert(address, Equals, "172.18.0.2")
+	address, err = info.IPAddress("a")
+	c.Assert(err, IsNil)
+	c.Assert(address, Equals, "172.17.0.2")
+	_, err = info.IPAddress("missing")
+	c.Assert(err, Equals, ErrNetworkNotConnected)
+}
+
 func (s *ContainerInfoTest) TestStarted(c *C) {
 	info := &ContainerInfo{
 		State: &types.ContainerState{
@@ -179,3 +228,34 @@ func (s *ContainerInfoTest) TestAddressDefault(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(value, Equals, "127.0.0.1")
 }
+
+func (s *ContainerInfoTest) TestRefreshFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	c.Assert(info.State.Running, Equals, true)
+
+	c.Assert(server.Exit(info.ID(), 2), IsNil)
+	c.Assert(info.Refresh(), IsNil)
+	c.Assert(info.State.Running, Equals, false)
+	c.Assert(info.State.ExitCode, Equals, 2)
+
+	c.Assert(dc.RemoveContainer(context.Background(), info.ID()), IsNil)
+	c.Assert(info.Refresh(), Equals, ErrContainerNotFound)
+}
+
+func (s *ContainerInfoTest) TestRefreshContextCancelled(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Assert(info.RefreshContext(ctx), ErrorMatches, ".*context canceled.*")
+}
@@ -0,0 +1,178 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+)
+
+// CopySource provides content which may be copied into a container. Use
+// FromHostPath, FromReader, FromTar or a FileMap to construct one.
+type CopySource interface {
+	archive(tw *tar.Writer) error
+}
+
+// CopyDestination receives content copied out of a container. Use
+// ToHostPath, ToWriter or a FileMap to construct one.
+type CopyDestination interface {
+	extract(reader io.Reader) error
+}
+
+type hostPathSource string
+
+// FromHostPath copies the file or directory at path on the host. The
+// base name of path is created inside of the destination directory.
+func FromHostPath(path string) CopySource {
+	return hostPathSource(path)
+}
+
+func (s hostPathSource) archive(tw *tar.Writer) error {
+	return tarPath(tw, string(s), true)
+}
+
+type readerSource struct {
+	name   string
+	reader io.Reader
+}
+
+// FromReader copies the content of reader into a single file with the
+// provided name.
+func FromReader(name string, reader io.Reader) CopySource {
+	return &readerSource{name: name, reader: reader}
+}
+
+func (s *readerSource) archive(tw *tar.Writer) error {
+	data, err := ioutil.ReadAll(s.reader)
+	if err != nil {
+		return err
+	}
+	return writeTarFile(tw, s.name, data)
+}
+
+type tarSource struct {
+	reader io.Reader
+}
+
+// FromTar copies the content of an existing tar stream.
+func FromTar(reader io.Reader) CopySource {
+	return &tarSource{reader: reader}
+}
+
+func (s *tarSource) archive(tw *tar.Writer) error {
+	tr := tar.NewReader(s.reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// FileMap maps file names to their content. As a CopySource each file is
+// created, along with any parent directories, relative to the destination
+// directory. As a CopyDestination regular files copied out of the
+// container are added to the map.
+type FileMap map[string][]byte
+
+func (f FileMap) archive(tw *tar.Writer) error {
+	names := []string{}
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeTarFile(tw, name, f[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f FileMap) extract(reader io.Reader) error {
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		f[header.Name] = data
+	}
+}
+
+type hostPathDestination string
+
+// ToHostPath extracts the copied content into the directory at path on
+// the host. The base name of the path copied from the container is
+// created inside of this directory.
+func ToHostPath(path string) CopyDestination {
+	return hostPathDestination(path)
+}
+
+func (d hostPathDestination) extract(reader io.Reader) error {
+	return extractTar(reader, string(d))
+}
+
+type writerDestination struct {
+	writer io.Writer
+}
+
+// ToWriter writes the tar stream produced by Docker to writer.
+func ToWriter(writer io.Writer) CopyDestination {
+	return &writerDestination{writer: writer}
+}
+
+func (d *writerDestination) extract(reader io.Reader) error {
+	_, err := io.Copy(d.writer, reader)
+	return err
+}
+
+// CopyTo copies source into the directory at path inside of the
+// container. The directory must already exist.
+func (c *ContainerInfo) CopyTo(ctx context.Context, path string, source CopySource) error {
+	return c.client.copyTo(ctx, c.ID(), path, source)
+}
+
+// CopyFrom copies the file or directory at path inside of the container
+// to destination.
+func (c *ContainerInfo) CopyFrom(ctx context.Context, path string, destination CopyDestination) error {
+	ctx, cancel := c.client.callContext(ctx)
+	defer cancel()
+	reader, _, err := c.client.docker.CopyFromContainer(ctx, c.ID(), path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close() // nolint: errcheck
+	return destination.extract(reader)
+}
+
+func (d *DockerClient) copyTo(ctx context.Context, id string, path string, source CopySource) error {
+	stream := tarStream(source.archive)
+	defer stream.Close() // nolint: errcheck
+
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	return d.docker.CopyToContainer(ctx, id, path, stream, types.CopyToContainerOptions{})
+}
@@ -0,0 +1,124 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type CopyTest struct{}
+
+var _ = Suite(&CopyTest{})
+
+func archiveSource(c *C, source CopySource) map[string]string {
+	return readTar(c, tarStream(source.archive))
+}
+
+func (s *CopyTest) TestSources(c *C) {
+	dir := filepath.Join(c.MkDir(), "config")
+	c.Assert(os.Mkdir(dir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "app.conf"), []byte("conf"), 0644), IsNil)
+	c.Assert(archiveSource(c, FromHostPath(dir)), DeepEquals, map[string]string{
+		"config/": "", "config/app.conf": "conf",
+	})
+
+	c.Assert(archiveSource(c, FromReader("init.sql", strings.NewReader("select 1;"))), DeepEquals,
+		map[string]string{"init.sql": "select 1;"})
+
+	c.Assert(archiveSource(c, FileMap{"a/b.txt": []byte("b"), "c.txt": []byte("c")}), DeepEquals,
+		map[string]string{"a/b.txt": "b", "c.txt": "c"})
+
+	buffer := &bytes.Buffer{}
+	tw := tar.NewWriter(buffer)
+	c.Assert(writeTarFile(tw, "from-tar", []byte("data")), IsNil)
+	c.Assert(tw.Close(), IsNil)
+	c.Assert(archiveSource(c, FromTar(buffer)), DeepEquals, map[string]string{"from-tar": "data"})
+}
+
+func (s *CopyTest) TestCopyToAndFrom(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	err := info.CopyTo(context.Background(), "/etc", FileMap{"app/app.conf": []byte("key=value")})
+	c.Assert(err, IsNil)
+	data, err := server.ReadFile(info.ID(), "/etc/app/app.conf")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "key=value")
+
+	files := FileMap{}
+	c.Assert(info.CopyFrom(context.Background(), "/etc/app", files), IsNil)
+	c.Assert(files, DeepEquals, FileMap{"app/app.conf": []byte("key=value")})
+
+	dir := c.MkDir()
+	c.Assert(info.CopyFrom(context.Background(), "/etc/app", ToHostPath(dir)), IsNil)
+	data, err = ioutil.ReadFile(filepath.Join(dir, "app", "app.conf"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "key=value")
+
+	buffer := &bytes.Buffer{}
+	c.Assert(info.CopyFrom(context.Background(), "/etc/app/app.conf", ToWriter(buffer)), IsNil)
+	c.Assert(readTar(c, buffer), DeepEquals, map[string]string{"app.conf": "key=value"})
+}
+
+func (s *CopyTest) TestCopyFromArtifact(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+	c.Assert(server.WriteFile(info.ID(), "/out/report.xml", []byte("<xml/>")), IsNil)
+
+	files := FileMap{}
+	c.Assert(info.CopyFrom(context.Background(), "/out/report.xml", files), IsNil)
+	c.Assert(string(files["report.xml"]), Equals, "<xml/>")
+}
+
+func (s *CopyTest) TestCopyErrors(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	err := info.CopyTo(context.Background(), "/missing", FileMap{"a": nil})
+	c.Assert(err, ErrorMatches, ".*Could not find the file /missing.*")
+	err = info.CopyFrom(context.Background(), "/missing", FileMap{})
+	c.Assert(err, ErrorMatches, ".*Could not find the file /missing.*")
+	err = info.CopyTo(context.Background(), "/tmp", FromHostPath(filepath.Join(c.MkDir(), "missing")))
+	c.Assert(err, ErrorMatches, ".*no such file or directory.*")
+}
+
+func (s *CopyTest) TestRunContainerFiles(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	input := NewClientInput(testImage)
+	input.Files = map[string]CopySource{}
+	input.Files["/tmp"] = FileMap{"fixtures/init.sql": []byte("select 1;")}
+	input.Files["/etc"] = FromReader("app.conf", strings.NewReader("conf"))
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	data, err := server.ReadFile(info.ID(), "/tmp/fixtures/init.sql")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "select 1;")
+	data, err = server.ReadFile(info.ID(), "/etc/app.conf")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "conf")
+}
+
+func (s *CopyTest) TestRunContainerFilesError(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	input := NewClientInput(testImage)
+	input.Files = map[string]CopySource{"/missing": FileMap{"a": nil}}
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, ErrorMatches, ".*Could not find the file /missing.*")
+}
@@ -0,0 +1,170 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	. "gopkg.in/check.v1"
+)
+
+// stubEngine is an Engine which records calls and returns canned
+// responses. Any method which is not overridden will panic.
+type stubEngine struct {
+	Engine
+	calls      []string
+	containers []types.Container
+	createErr  error
+	startErr   error
+	removeErr  error
+}
+
+func (e *stubEngine) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error) {
+	e.calls = append(e.calls, "create")
+	if e.createErr != nil {
+		err := e.createErr
+		e.createErr = nil
+		return container.ContainerCreateCreatedBody{}, err
+	}
+	return container.ContainerCreateCreatedBody{ID: "stub", Warnings: []string{"warning"}}, nil
+}
+
+func (e *stubEngine) ContainerStart(ctx context.Context, id string, options types.ContainerStartOptions) error {
+	e.calls = append(e.calls, "start")
+	return e.startErr
+}
+
+func (e *stubEngine) ContainerRemove(ctx context.Context, id string, options types.ContainerRemoveOptions) error {
+	e.calls = append(e.calls, "remove")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return e.removeErr
+}
+
+func (e *stubEngine) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	e.calls = append(e.calls, "list")
+	return e.containers, nil
+}
+
+func (e *stubEngine) ContainerInspect(ctx context.Context, id string) (types.ContainerJSON, error) {
+	e.calls = append(e.calls, "inspect")
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: id, State: &types.ContainerState{}},
+	}, nil
+}
+
+func (e *stubEngine) CopyToContainer(ctx context.Context, id string, path string, content io.Reader, options types.CopyToContainerOptions) error {
+	e.calls = append(e.calls, "copy "+path)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := io.Copy(ioutil.Discard, content)
+	return err
+}
+
+func (e *stubEngine) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	e.calls = append(e.calls, "pull")
+	return ioutil.NopCloser(strings.NewReader("")), nil
+}
+
+type EngineTest struct{}
+
+var _ = Suite(&EngineTest{})
+
+func (s *EngineTest) TestNewClientWithEngine(c *C) {
+	engine := &stubEngine{}
+	dc := NewClientWithEngine(engine)
+	c.Assert(dc.docker, Equals, Engine(engine))
+}
+
+func (s *EngineTest) TestContainerInfoNotFound(c *C) {
+	dc := NewClientWithEngine(&stubEngine{})
+	_, err := dc.ContainerInfo(context.Background(), "foobar")
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
+
+func (s *EngineTest) TestRunContainerPullsMissingImage(c *C) {
+	engine := &stubEngine{
+		containers: []types.Container{{ID: "stub"}},
+		createErr:  notFoundError{errors.New("No such image: test")},
+	}
+	dc := NewClientWithEngine(engine)
+	info, err := dc.RunContainer(context.Background(), NewClientInput("test"))
+	c.Assert(err, IsNil)
+	c.Assert(info.ID(), Equals, "stub")
+	c.Assert(info.Warnings, DeepEquals, []string{"warning"})
+	c.Assert(engine.calls, DeepEquals, []string{"create", "pull", "create", "start", "list", "inspect"})
+}
+
+func (s *EngineTest) TestRunContainerCopiesFilesBeforeStart(c *C) {
+	engine := &stubEngine{containers: []types.Container{{ID: "stub"}}}
+	dc := NewClientWithEngine(engine)
+	input := NewClientInput("test")
+	input.Files = map[string]CopySource{"/b": FileMap{}, "/a": FileMap{}}
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(engine.calls, DeepEquals, []string{"create", "copy /a", "copy /b", "start", "list", "inspect"})
+}
+
+func (s *EngineTest) TestRunContainerRemovesOnStartFailure(c *C) {
+	engine := &stubEngine{
+		containers: []types.Container{{ID: "stub"}},
+		startErr:   errors.New("port is already allocated"),
+	}
+	dc := NewClientWithEngine(engine)
+	info, err := dc.RunContainer(context.Background(), NewClientInput("test"))
+	c.Assert(info, IsNil)
+	c.Assert(err, Equals, engine.startErr)
+	c.Assert(engine.calls, DeepEquals, []string{"create", "start", "remove"})
+}
+
+func (s *EngineTest) TestRunContainerRemovesOnInfoFailure(c *C) {
+	engine := &stubEngine{}
+	dc := NewClientWithEngine(engine)
+	info, err := dc.RunContainer(context.Background(), NewClientInput("test"))
+	c.Assert(info, IsNil)
+	c.Assert(err, Equals, ErrContainerNotFound)
+	c.Assert(engine.calls, DeepEquals, []string{"create", "start", "list", "remove"})
+}
+
+func (s *EngineTest) TestRunContainerRemovesOnCancel(c *C) {
+	engine := &stubEngine{containers: []types.Container{{ID: "stub"}}}
+	dc := NewClientWithEngine(engine)
+	input := NewClientInput("test")
+	input.Files = map[string]CopySource{"/a": FileMap{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := dc.RunContainer(ctx, input)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(engine.calls, DeepEquals, []string{"create", "copy /a", "remove"})
+}
+
+func (s *EngineTest) TestRunContainerRollbackError(c *C) {
+	engine := &stubEngine{
+		startErr:  errors.New("port is already allocated"),
+		removeErr: errors.New("removal already in progress"),
+	}
+	dc := NewClientWithEngine(engine)
+	_, err := dc.RunContainer(context.Background(), NewClientInput("test"))
+	c.Assert(err, ErrorMatches, "port is already allocated \\(removing container stub also failed: removal already in progress\\)")
+	c.Assert(errors.Is(err, engine.startErr), Equals, true)
+	c.Assert(errors.Is(err, engine.removeErr), Equals, true)
+	var rollback *RollbackError
+	c.Assert(errors.As(err, &rollback), Equals, true)
+	c.Assert(rollback.ID, Equals, "stub")
+}
+
+// notFoundError satisfies the interface used by client.IsErrNotFound.
+type notFoundError struct {
+	error
+}
+
+func (notFoundError) NotFound() bool {
+	return true
+}
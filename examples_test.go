@@ -4,10 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net"
 	"time"
 
 	"github.com/opalmer/dockertest"
+	"github.com/opalmer/dockertest/wait"
 	. "gopkg.in/check.v1"
 )
 
@@ -77,25 +77,10 @@ func ExampleDockerClient_Service() {
 	})
 
 	// Construct the service and tell it how to handle waiting
-	// for the container to start.
+	// for the container to start. See the wait package for other
+	// ways to wait.
 	service := client.Service(input)
-	service.Ping = func(input *dockertest.PingInput) error {
-		port, err := input.Container.Port(80)
-		if err != nil {
-			return err // Will cause Run() to call Terminate()
-		}
-
-		for {
-			_, err := net.Dial(string(port.Protocol), fmt.Sprintf("%s:%d", port.Address, port.Public))
-			if err != nil {
-				time.Sleep(time.Millisecond * 100)
-				continue
-			}
-			break
-		}
-
-		return nil
-	}
+	service.Ping = wait.TCP(80, wait.WithTimeout(time.Second*30))
 
 	// Starts the container, runs Ping() and waits for it to return. If Ping()
 	// fails the container will be terminated and Run() will return an error.
@@ -0,0 +1,150 @@
+package dockertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// execPollInterval is how often Exec checks for the exit code of a
+// command whose output has ended but which has not been reported as
+// finished yet.
+const execPollInterval = time.Millisecond * 50
+
+// ErrExecCommandNotProvided is returned by Exec if the command to run
+// was empty.
+var ErrExecCommandNotProvided = errors.New("exec command not provided")
+
+// ExecOptions provides optional settings for ContainerInfo.Exec.
+type ExecOptions struct {
+	// Env is a list of KEY=VALUE pairs set in addition to the
+	// container's own environment.
+	Env []string
+
+	// WorkingDir is the directory to run the command in. The API
+	// version this package targets cannot set the working directory
+	// of an exec so the command is wrapped with /bin/sh, which must
+	// exist in the image, when this is provided.
+	WorkingDir string
+
+	// User is the user, and optionally group, to run the command as.
+	User string
+
+	// Stdin, if set, is copied to the command's standard input.
+	Stdin io.Reader
+
+	// Tty allocates a pseudo-TTY for the command. Docker merges stderr
+	// into stdout when a TTY is used.
+	Tty bool
+}
+
+// ExecResult is returned by ContainerInfo.Exec once the command has
+// finished.
+type ExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Exec runs cmd inside of the container and waits for it to finish. A
+// non-zero exit code is not treated as an error, check ExitCode on the
+// result instead. The options argument may be nil.
+func (c *ContainerInfo) Exec(ctx context.Context, cmd []string, options *ExecOptions) (*ExecResult, error) {
+	if len(cmd) == 0 {
+		return nil, ErrExecCommandNotProvided
+	}
+	if options == nil {
+		options = &ExecOptions{}
+	}
+	return c.client.exec(ctx, c.ID(), cmd, options)
+}
+
+func (d *DockerClient) exec(ctx context.Context, id string, cmd []string, options *ExecOptions) (*ExecResult, error) {
+	if options.WorkingDir != "" {
+		cmd = append([]string{"/bin/sh", "-c", `cd "$1" && shift && exec "$@"`, "sh", options.WorkingDir}, cmd...)
+	}
+	config := types.ExecConfig{
+		User:         options.User,
+		Tty:          options.Tty,
+		AttachStdin:  options.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          options.Env,
+		Cmd:          cmd,
+	}
+
+	createCtx, cancel := d.callContext(ctx)
+	created, err := d.docker.ContainerExecCreate(createCtx, id, config)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	// The per-call timeout is not applied to the attached stream
+	// because commands may legitimately run for longer than a single
+	// API call would.
+	attached, err := d.docker.ContainerExecAttach(ctx, created.ID, config)
+	if err != nil {
+		return nil, err
+	}
+	defer attached.Close()
+
+	// Closing the connection unblocks the reads below if ctx is
+	// cancelled before the command finishes.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			attached.Close()
+		case <-done:
+		}
+	}()
+
+	if options.Stdin != nil {
+		go func() {
+			io.Copy(attached.Conn, options.Stdin) // nolint: errcheck
+			attached.CloseWrite()                 // nolint: errcheck
+		}()
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if options.Tty {
+		_, err = io.Copy(stdout, attached.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, attached.Reader)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		inspectCtx, cancel := d.callContext(ctx)
+		inspection, err := d.docker.ContainerExecInspect(inspectCtx, created.ID)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if !inspection.Running {
+			return &ExecResult{
+				Stdout:   stdout.String(),
+				Stderr:   stderr.String(),
+				ExitCode: inspection.ExitCode,
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(execPollInterval):
+		}
+	}
+}
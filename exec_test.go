@@ -0,0 +1,116 @@
+package dockertest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type ExecTest struct{}
+
+var _ = Suite(&ExecTest{})
+
+func runFakeContainer(c *C, dc *DockerClient, server *fakeengine.Server, image *fakeengine.Image) *ContainerInfo {
+	server.AddImage(testImage, image)
+	info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	return info
+}
+
+func (s *ExecTest) TestExec(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{
+		Exec: func(exec *fakeengine.Exec) int {
+			fmt.Fprintln(exec.Stdout, "out") // nolint: errcheck
+			fmt.Fprintln(exec.Stderr, "err") // nolint: errcheck
+			return 3
+		},
+	})
+
+	result, err := info.Exec(context.Background(), []string{"redis-cli", "ping"}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(result, DeepEquals, &ExecResult{Stdout: "out\n", Stderr: "err\n", ExitCode: 3})
+}
+
+func (s *ExecTest) TestExecOptions(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	var received *fakeengine.Exec
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{
+		Exec: func(exec *fakeengine.Exec) int {
+			received = exec
+			return 0
+		},
+	})
+
+	_, err := info.Exec(context.Background(), []string{"ls"}, &ExecOptions{
+		Env:        []string{"A=1"},
+		WorkingDir: "/tmp",
+		User:       "nobody",
+	})
+	c.Assert(err, IsNil)
+	c.Assert(received.Env, DeepEquals, []string{"A=1"})
+	c.Assert(received.User, Equals, "nobody")
+	c.Assert(received.Cmd, DeepEquals, []string{
+		"/bin/sh", "-c", `cd "$1" && shift && exec "$@"`, "sh", "/tmp", "ls"})
+}
+
+func (s *ExecTest) TestExecStdin(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	result, err := info.Exec(context.Background(), []string{"cat"}, &ExecOptions{
+		Stdin: strings.NewReader("hello"),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result, DeepEquals, &ExecResult{Stdout: "hello"})
+}
+
+func (s *ExecTest) TestExecTty(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	result, err := info.Exec(context.Background(), []string{"missing"}, &ExecOptions{Tty: true})
+	c.Assert(err, IsNil)
+	c.Assert(result.ExitCode, Equals, 126)
+	c.Assert(result.Stdout, Matches, ".*executable file not found.*\n")
+	c.Assert(result.Stderr, Equals, "")
+}
+
+func (s *ExecTest) TestExecContextCancelled(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	release := make(chan struct{})
+	defer close(release)
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{
+		Exec: func(exec *fakeengine.Exec) int {
+			<-release
+			return 0
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	_, err := info.Exec(ctx, []string{"sleep", "60"}, nil)
+	c.Assert(err, Equals, context.DeadlineExceeded)
+}
+
+func (s *ExecTest) TestExecErrors(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	_, err := info.Exec(context.Background(), nil, nil)
+	c.Assert(err, Equals, ErrExecCommandNotProvided)
+
+	c.Assert(server.Exit(info.ID(), 0), IsNil)
+	_, err = info.Exec(context.Background(), []string{"true"}, nil)
+	c.Assert(err, ErrorMatches, ".*is not running")
+}
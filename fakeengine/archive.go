@@ -0,0 +1,284 @@
+package fakeengine
+
+import (
+	"archive/tar"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+// defaultDirectories exist in the filesystem of every container.
+var defaultDirectories = []string{
+	"/", "/bin", "/etc", "/home", "/root", "/tmp", "/usr", "/var",
+}
+
+// fileEntry is a single file, directory or symlink in the filesystem of
+// a container.
+type fileEntry struct {
+	mode  os.FileMode
+	data  []byte
+	link  string
+	mtime time.Time
+}
+
+// newFilesystem returns the initial filesystem for a container created
+// from image.
+func newFilesystem(image *Image) map[string]*fileEntry {
+	now := time.Now().UTC()
+	files := map[string]*fileEntry{}
+	for _, dir := range defaultDirectories {
+		files[dir] = &fileEntry{mode: os.ModeDir | 0755, mtime: now}
+	}
+	for name, content := range image.Files {
+		writeFile(files, name, &fileEntry{mode: 0644, data: []byte(content), mtime: now})
+	}
+	return files
+}
+
+// writeFile adds entry to files, creating any missing parent directories.
+func writeFile(files map[string]*fileEntry, name string, entry *fileEntry) {
+	name = path.Clean("/" + name)
+	for dir := path.Dir(name); ; dir = path.Dir(dir) {
+		if _, ok := files[dir]; !ok {
+			files[dir] = &fileEntry{mode: os.ModeDir | 0755, mtime: entry.mtime}
+		}
+		if dir == "/" {
+			break
+		}
+	}
+	files[name] = entry
+}
+
+func (e *fileEntry) stat(name string) types.ContainerPathStat {
+	return types.ContainerPathStat{
+		Name:       path.Base(name),
+		Size:       int64(len(e.data)),
+		Mode:       e.mode,
+		Mtime:      e.mtime,
+		LinkTarget: e.link,
+	}
+}
+
+// ReadFile returns the content of a file inside of the container.
+func (s *Server) ReadFile(id string, name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		return nil, ErrNoSuchContainer
+	}
+	entry, ok := c.files[path.Clean("/"+name)]
+	if !ok || !entry.mode.IsRegular() {
+		return nil, errors.Errorf("%s: no such file", name)
+	}
+	return append([]byte{}, entry.data...), nil
+}
+
+// WriteFile writes a file inside of the container, creating any missing
+// parent directories. This may be used to simulate a container producing
+// output files.
+func (s *Server) WriteFile(id string, name string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		return ErrNoSuchContainer
+	}
+	writeFile(c.files, name, &fileEntry{mode: 0644, data: append([]byte{}, data...), mtime: time.Now().UTC()})
+	return nil
+}
+
+// archiveTarget returns the container and filesystem entry for an
+// archive request, writing an error response if either does not exist.
+// The caller must hold s.mu.
+func (s *Server) archiveTarget(w http.ResponseWriter, r *http.Request, id string) (*fakeContainer, string, *fileEntry, bool) {
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", id)
+		return nil, "", nil, false
+	}
+	name := r.URL.Query().Get("path")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Bad parameters: path cannot be empty")
+		return nil, "", nil, false
+	}
+	name = path.Clean("/" + name)
+	entry, ok := c.files[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Could not find the file %s in container %s", name, id)
+		return nil, "", nil, false
+	}
+	return c, name, entry, true
+}
+
+func setStatHeader(w http.ResponseWriter, name string, entry *fileEntry) {
+	encoded, _ := json.Marshal(entry.stat(name)) // nolint: errcheck
+	w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(encoded))
+}
+
+func (s *Server) archiveStat(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, name, entry, ok := s.archiveTarget(w, r, args[0]); ok {
+		setStatHeader(w, name, entry)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *Server) archiveGet(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, name, entry, ok := s.archiveTarget(w, r, args[0])
+	if !ok {
+		return
+	}
+
+	// Entries are named relative to the parent of the requested path,
+	// as docker cp does.
+	names := []string{}
+	for file := range c.files {
+		if file == name || (entry.mode.IsDir() && strings.HasPrefix(file, strings.TrimSuffix(name, "/")+"/")) {
+			names = append(names, file)
+		}
+	}
+	sort.Strings(names)
+
+	setStatHeader(w, name, entry)
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.WriteHeader(http.StatusOK)
+	tw := tar.NewWriter(w)
+	base := path.Dir(name)
+	for _, file := range names {
+		current := c.files[file]
+		relative := strings.TrimPrefix(strings.TrimPrefix(file, base), "/")
+		if relative == "" {
+			relative = "."
+		}
+		header := &tar.Header{
+			Name:    relative,
+			Mode:    int64(current.mode.Perm()),
+			ModTime: current.mtime,
+		}
+		switch {
+		case current.mode.IsDir():
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+		case current.mode&os.ModeSymlink != 0:
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = current.link
+		default:
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(current.data))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return
+		}
+		tw.Write(current.data) // nolint: errcheck
+	}
+	tw.Close() // nolint: errcheck
+}
+
+// readOnly returns the reason name may not be modified, because it is
+// on a read only mount or the root filesystem is read only, or "".
+func (c *fakeContainer) readOnly(name string) string {
+	var closest *types.MountPoint
+	for i, point := range c.mounts {
+		if name == point.Destination || strings.HasPrefix(name, point.Destination+"/") {
+			if closest == nil || len(point.Destination) > len(closest.Destination) {
+				closest = &c.mounts[i]
+			}
+		}
+	}
+	switch {
+	case closest != nil && !closest.RW:
+		return "mounted volume is marked read-only"
+	case closest == nil && c.hostConfig.ReadonlyRootfs:
+		return "container rootfs is marked read-only"
+	}
+	return ""
+}
+
+func (s *Server) archivePut(w http.ResponseWriter, r *http.Request, args []string) {
+	noOverwrite := r.URL.Query().Get("noOverwriteDirNonDir") == "true"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, name, entry, ok := s.archiveTarget(w, r, args[0])
+	if !ok {
+		return
+	}
+	if !entry.mode.IsDir() {
+		writeError(w, http.StatusBadRequest, "extraction point is not a directory")
+		return
+	}
+	if reason := c.readOnly(name); reason != "" {
+		writeError(w, http.StatusForbidden, "%s", reason)
+		return
+	}
+
+	// Extract into a copy so a failure part way through leaves the
+	// filesystem unchanged.
+	files := map[string]*fileEntry{}
+	for key, value := range c.files {
+		files[key] = value
+	}
+	now := time.Now().UTC()
+	tr := tar.NewReader(r.Body)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		target := path.Join(name, header.Name)
+		if target != name && !strings.HasPrefix(target, strings.TrimSuffix(name, "/")+"/") {
+			writeError(w, http.StatusBadRequest, "invalid path in archive: %s", header.Name)
+			return
+		}
+
+		added := &fileEntry{mode: os.FileMode(header.Mode).Perm(), mtime: now}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			added.mode |= os.ModeDir
+		case tar.TypeSymlink:
+			added.mode |= os.ModeSymlink
+			added.link = header.Linkname
+		case tar.TypeReg, tar.TypeRegA:
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "%s", err)
+				return
+			}
+			added.data = data
+		default:
+			continue
+		}
+
+		if existing, ok := files[target]; ok {
+			if existing.mode.IsDir() && added.mode.IsDir() {
+				continue
+			}
+			if noOverwrite && existing.mode.IsDir() != added.mode.IsDir() {
+				writeError(w, http.StatusBadRequest,
+					"cannot overwrite directory %q with non-directory %q", target, header.Name)
+				return
+			}
+		}
+		writeFile(files, target, added)
+	}
+	c.files = files
+	w.WriteHeader(http.StatusOK)
+}
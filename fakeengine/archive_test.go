@@ -0,0 +1,93 @@
+package fakeengine
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	. "gopkg.in/check.v1"
+)
+
+func (s *ServerTest) TestArchive(c *C) {
+	s.server.AddImage("test", &Image{Files: map[string]string{"/etc/motd": "hello"}})
+	id := s.create(c, "test", nil)
+
+	content := buildContext(c, map[string]string{"dir/file": "data"})
+	err := s.docker.CopyToContainer(context.Background(), id, "/tmp", content, types.CopyToContainerOptions{})
+	c.Assert(err, IsNil)
+	data, err := s.server.ReadFile(id, "/tmp/dir/file")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "data")
+
+	stat, err := s.docker.ContainerStatPath(context.Background(), id, "/etc/motd")
+	c.Assert(err, IsNil)
+	c.Assert(stat.Name, Equals, "motd")
+	c.Assert(stat.Size, Equals, int64(5))
+
+	reader, stat, err := s.docker.CopyFromContainer(context.Background(), id, "/tmp/dir")
+	c.Assert(err, IsNil)
+	defer reader.Close() // nolint: errcheck
+	c.Assert(stat.Mode.IsDir(), Equals, true)
+	names := map[string]string{}
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, IsNil)
+		body, err := ioutil.ReadAll(tr)
+		c.Assert(err, IsNil)
+		names[header.Name] = string(body)
+	}
+	c.Assert(names, DeepEquals, map[string]string{"dir/": "", "dir/file": "data"})
+}
+
+func (s *ServerTest) TestArchiveErrors(c *C) {
+	s.server.AddImage("test", &Image{Files: map[string]string{"/etc/motd": "hello"}})
+	id := s.create(c, "test", nil)
+
+	_, err := s.docker.ContainerStatPath(context.Background(), id, "/missing")
+	c.Assert(err, NotNil)
+	_, _, err = s.docker.CopyFromContainer(context.Background(), id, "/missing")
+	c.Assert(err, ErrorMatches, ".*Could not find the file /missing.*")
+
+	content := buildContext(c, map[string]string{"file": "data"})
+	err = s.docker.CopyToContainer(context.Background(), id, "/etc/motd", content, types.CopyToContainerOptions{})
+	c.Assert(err, ErrorMatches, ".*extraction point is not a directory")
+
+	content = buildContext(c, map[string]string{"etc": "data"})
+	err = s.docker.CopyToContainer(context.Background(), id, "/", content, types.CopyToContainerOptions{})
+	c.Assert(err, ErrorMatches, `.*cannot overwrite directory "/etc" with non-directory "etc"`)
+
+	c.Assert(s.server.WriteFile("missing", "/a", nil), Equals, ErrNoSuchContainer)
+	_, err = s.server.ReadFile(id, "/etc")
+	c.Assert(err, ErrorMatches, "/etc: no such file")
+}
+
+func (s *ServerTest) TestArchivePutReadOnly(c *C) {
+	s.server.AddImage("test", &Image{})
+	created, err := s.docker.ContainerCreate(
+		context.Background(), &container.Config{Image: "test"},
+		&container.HostConfig{
+			ReadonlyRootfs: true,
+			Mounts: []mount.Mount{
+				{Type: mount.TypeTmpfs, Target: "/scratch"},
+				{Type: mount.TypeVolume, Target: "/config", ReadOnly: true},
+			},
+		}, &network.NetworkingConfig{}, "")
+	c.Assert(err, IsNil)
+
+	put := func(dir string) error {
+		return s.docker.CopyToContainer(context.Background(), created.ID, dir,
+			buildContext(c, map[string]string{"file": "data"}), types.CopyToContainerOptions{})
+	}
+	c.Assert(put("/scratch"), IsNil)
+	c.Assert(put("/tmp"), ErrorMatches, ".*container rootfs is marked read-only.*")
+	c.Assert(put("/config"), ErrorMatches, ".*mounted volume is marked read-only.*")
+}
@@ -0,0 +1,397 @@
+package fakeengine
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/pkg/errors"
+)
+
+var (
+	exitCommand  = regexp.MustCompile(`^exit\s+([0-9]+)$`)
+	argReference = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?`)
+)
+
+// instruction is a single parsed line of a Dockerfile.
+type instruction struct {
+	command string
+	args    string
+	raw     string
+}
+
+// stage is a single FROM section of a Dockerfile.
+type stage struct {
+	name   string
+	config container.Config
+}
+
+// parseDockerfile splits a Dockerfile into instructions, joining line
+// continuations and removing comments.
+func parseDockerfile(data []byte) []instruction {
+	instructions := []instruction{}
+	current := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") || (line == "" && current == "") {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			current += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		current += line
+		fields := strings.SplitN(current, " ", 2)
+		entry := instruction{command: strings.ToUpper(fields[0]), raw: current}
+		if len(fields) == 2 {
+			entry.args = strings.TrimSpace(fields[1])
+		}
+		instructions = append(instructions, entry)
+		current = ""
+	}
+	return instructions
+}
+
+// parseCommand converts the exec or shell form used by CMD and
+// ENTRYPOINT into a slice.
+func parseCommand(value string) []string {
+	command := []string{}
+	if strings.HasPrefix(value, "[") && json.Unmarshal([]byte(value), &command) == nil {
+		return command
+	}
+	return []string{"/bin/sh", "-c", value}
+}
+
+// parsePairs parses the key=value pairs used by ENV and LABEL, also
+// accepting the legacy "ENV key value" form.
+func parsePairs(value string) map[string]string {
+	pairs := map[string]string{}
+	if !strings.Contains(strings.SplitN(value, " ", 2)[0], "=") {
+		fields := strings.SplitN(value, " ", 2)
+		if len(fields) == 2 {
+			pairs[fields[0]] = strings.TrimSpace(fields[1])
+		}
+		return pairs
+	}
+	for _, field := range strings.Fields(value) {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) == 2 {
+			if unquoted, err := strconv.Unquote(parts[1]); err == nil {
+				parts[1] = unquoted
+			}
+			pairs[parts[0]] = parts[1]
+		}
+	}
+	return pairs
+}
+
+// readContext reads every file in the tar build context into memory.
+func readContext(reader io.Reader) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid build context")
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(header.Name)] = data
+	}
+}
+
+// hasContextPath returns true if name is a file or directory within the
+// build context.
+func hasContextPath(files map[string][]byte, name string) bool {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if name == "." {
+		return true
+	}
+	for file := range files {
+		if file == name || strings.HasPrefix(file, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) imageBuild(w http.ResponseWriter, r *http.Request, args []string) {
+	query := r.URL.Query()
+	dockerfile := query.Get("dockerfile")
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	buildArgs := map[string]*string{}
+	if value := query.Get("buildargs"); value != "" {
+		if err := json.Unmarshal([]byte(value), &buildArgs); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+	}
+	labels := map[string]string{}
+	if value := query.Get("labels"); value != "" {
+		if err := json.Unmarshal([]byte(value), &labels); err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+	}
+
+	files, err := readContext(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	data, ok := files[path.Clean(dockerfile)]
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Cannot locate specified Dockerfile: %s", dockerfile)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	fail := func(format string, args ...interface{}) {
+		message := fmt.Sprintf(format, args...)
+		encoder.Encode(map[string]interface{}{ // nolint: errcheck
+			"errorDetail": map[string]interface{}{"message": message},
+			"error":       message,
+		})
+	}
+
+	result, err := s.build(parseDockerfile(data), files, buildArgs, query.Get("target"), func(line string) {
+		encoder.Encode(map[string]string{"stream": line + "\n"}) // nolint: errcheck
+	})
+	if err != nil {
+		fail("%s", err)
+		return
+	}
+	for key, value := range labels {
+		result.Labels[key] = value
+	}
+
+	hash := sha256.New()
+	hash.Write(data) // nolint: errcheck
+	keys := []string{}
+	for name := range files {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, files[name]) // nolint: errcheck
+	}
+	encoded, _ := json.Marshal(result) // nolint: errcheck
+	hash.Write(encoded)                // nolint: errcheck
+	image := &Image{ID: fmt.Sprintf("sha256:%x", hash.Sum(nil)), Config: *result}
+
+	s.mu.Lock()
+	s.images[image.ID] = image
+	for _, tag := range query["t"] {
+		s.images[normalize(tag)] = image
+	}
+	s.mu.Unlock()
+
+	aux := json.RawMessage(fmt.Sprintf(`{"ID":%q}`, image.ID))
+	encoder.Encode(map[string]interface{}{"aux": &aux})                                                        // nolint: errcheck
+	encoder.Encode(map[string]string{"stream": "Successfully built " + image.ID[len("sha256:"):][:12] + "\n"}) // nolint: errcheck
+	for _, tag := range query["t"] {
+		encoder.Encode(map[string]string{"stream": "Successfully tagged " + normalize(tag) + "\n"}) // nolint: errcheck
+	}
+}
+
+// build evaluates the Dockerfile instructions and returns the config of
+// the resulting image.
+func (s *Server) build(instructions []instruction, files map[string][]byte, buildArgs map[string]*string, target string, output func(string)) (*container.Config, error) {
+	stages := []*stage{}
+	args := map[string]string{}
+	consumed := map[string]bool{}
+
+	expand := func(value string) string {
+		return argReference.ReplaceAllStringFunc(value, func(match string) string {
+			name := argReference.FindStringSubmatch(match)[1]
+			if arg, ok := args[name]; ok {
+				return arg
+			}
+			return match
+		})
+	}
+
+	for i, entry := range instructions {
+		output(fmt.Sprintf("Step %d/%d : %s", i+1, len(instructions), entry.raw))
+		value := expand(entry.args)
+
+		if entry.command == "ARG" {
+			parts := strings.SplitN(value, "=", 2)
+			consumed[parts[0]] = true
+			if provided, ok := buildArgs[parts[0]]; ok && provided != nil {
+				args[parts[0]] = *provided
+			} else if len(parts) == 2 {
+				args[parts[0]] = parts[1]
+			}
+			continue
+		}
+
+		if entry.command == "FROM" {
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				return nil, errors.New("FROM requires an argument")
+			}
+			next := &stage{}
+			if len(fields) == 3 && strings.EqualFold(fields[1], "AS") {
+				next.name = fields[2]
+			}
+			base, err := s.baseConfig(fields[0], stages)
+			if err != nil {
+				return nil, err
+			}
+			next.config = base
+			stages = append(stages, next)
+			continue
+		}
+
+		if len(stages) == 0 {
+			return nil, errors.Errorf("Please provide a source image with `from` prior to %s", strings.ToLower(entry.command))
+		}
+		current := stages[len(stages)-1]
+		config := &current.config
+
+		switch entry.command {
+		case "ENV":
+			for key, value := range parsePairs(value) {
+				config.Env = append(config.Env, key+"="+value)
+			}
+		case "LABEL":
+			for key, value := range parsePairs(value) {
+				config.Labels[key] = value
+			}
+		case "CMD":
+			config.Cmd = parseCommand(value)
+		case "ENTRYPOINT":
+			config.Entrypoint = parseCommand(value)
+		case "HEALTHCHECK":
+			healthcheck, err := parseHealthcheck(value)
+			if err != nil {
+				return nil, err
+			}
+			config.Healthcheck = healthcheck
+		case "WORKDIR":
+			config.WorkingDir = value
+		case "USER":
+			config.User = value
+		case "EXPOSE":
+			for _, field := range strings.Fields(value) {
+				port, err := nat.NewPort(nat.SplitProtoPort(field))
+				if err != nil {
+					return nil, err
+				}
+				config.ExposedPorts[port] = struct{}{}
+			}
+		case "RUN":
+			code := 0
+			if value == "false" {
+				code = 1
+			} else if match := exitCommand.FindStringSubmatch(value); match != nil {
+				code, _ = strconv.Atoi(match[1]) // nolint: errcheck
+			}
+			if code != 0 {
+				return nil, errors.Errorf("The command '/bin/sh -c %s' returned a non-zero code: %d", value, code)
+			}
+		case "COPY", "ADD":
+			fields := strings.Fields(value)
+			for _, source := range fields[:len(fields)-1] {
+				if strings.HasPrefix(source, "--") || strings.ContainsAny(source, "*?[") {
+					continue
+				}
+				if !hasContextPath(files, source) {
+					return nil, errors.Errorf("%s failed: stat %s: no such file or directory", entry.command, source)
+				}
+			}
+		}
+	}
+
+	if len(stages) == 0 {
+		return nil, errors.New("the Dockerfile (Dockerfile) cannot be empty")
+	}
+
+	unused := []string{}
+	for name := range buildArgs {
+		if !consumed[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		output(fmt.Sprintf("[Warning] One or more build-args %v were not consumed", unused))
+	}
+
+	if target == "" {
+		config := stages[len(stages)-1].config
+		return &config, nil
+	}
+	for _, entry := range stages {
+		if entry.name == target {
+			config := entry.config
+			return &config, nil
+		}
+	}
+	return nil, errors.Errorf("failed to reach build target %s in Dockerfile", target)
+}
+
+// baseConfig returns a copy of the config of the image named by a FROM
+// instruction, which may be an earlier stage, a local image or an image
+// available to pull.
+func (s *Server) baseConfig(name string, stages []*stage) (container.Config, error) {
+	config := container.Config{}
+	if name != "scratch" {
+		found := false
+		for _, previous := range stages {
+			if previous.name == name {
+				config, found = previous.config, true
+			}
+		}
+		if !found {
+			s.mu.Lock()
+			image, ok := s.lookupImage(name)
+			if !ok {
+				if image, ok = s.registry[normalize(name)]; ok {
+					s.images[normalize(name)] = image
+				}
+			}
+			s.mu.Unlock()
+			if !ok {
+				return config, errors.Errorf(
+					"pull access denied for %s, repository does not exist or may require 'docker login'", name)
+			}
+			config = image.Config
+		}
+	}
+
+	// Copy reference types so stages do not share state.
+	config.Env = append([]string{}, config.Env...)
+	labels := map[string]string{}
+	for key, value := range config.Labels {
+		labels[key] = value
+	}
+	config.Labels = labels
+	ports := nat.PortSet{}
+	for port := range config.ExposedPorts {
+		ports[port] = struct{}{}
+	}
+	config.ExposedPorts = ports
+	return config, nil
+}
@@ -0,0 +1,88 @@
+package fakeengine
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/go-connections/nat"
+	. "gopkg.in/check.v1"
+)
+
+func buildContext(c *C, files map[string]string) io.Reader {
+	buffer := &bytes.Buffer{}
+	tw := tar.NewWriter(buffer)
+	for name, content := range files {
+		c.Assert(tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}), IsNil)
+		_, err := tw.Write([]byte(content))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(tw.Close(), IsNil)
+	return buffer
+}
+
+func (s *ServerTest) build(c *C, files map[string]string, options types.ImageBuildOptions) string {
+	response, err := s.docker.ImageBuild(context.Background(), buildContext(c, files), options)
+	c.Assert(err, IsNil)
+	defer response.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	return string(body)
+}
+
+func (s *ServerTest) TestBuild(c *C) {
+	s.server.AddRemoteImage("alpine", &Image{Config: container.Config{Env: []string{"PATH=/bin"}}})
+	value := "2"
+	body := s.build(c, map[string]string{
+		"Dockerfile": "FROM alpine\nARG VERSION=1\nENV VERSION=$VERSION\n" +
+			"LABEL a=b\nEXPOSE 80\nCOPY app /app\nCMD [\"/app\"]\n",
+		"app": "binary",
+	}, types.ImageBuildOptions{
+		Tags:      []string{"built:latest"},
+		Labels:    map[string]string{"c": "d"},
+		BuildArgs: map[string]*string{"VERSION": &value},
+	})
+	c.Assert(body, Matches, `(?s).*Step 1/7 : FROM alpine.*"aux":\{"ID":"sha256:[0-9a-f]{64}"\}.*Successfully built [0-9a-f]{12}.*Successfully tagged built:latest.*`)
+	c.Assert(s.server.HasImage("built"), Equals, true)
+
+	s.server.mu.Lock()
+	image, _ := s.server.lookupImage("built")
+	s.server.mu.Unlock()
+	c.Assert(image.Config.Env, DeepEquals, []string{"PATH=/bin", "VERSION=2"})
+	c.Assert(image.Config.Labels, DeepEquals, map[string]string{"a": "b", "c": "d"})
+	c.Assert(image.Config.Cmd, DeepEquals, strslice.StrSlice{"/app"})
+	c.Assert(image.Config.ExposedPorts, DeepEquals, nat.PortSet{"80/tcp": {}})
+}
+
+func (s *ServerTest) TestBuildTarget(c *C) {
+	files := map[string]string{"Dockerfile": "FROM scratch AS base\nLABEL stage=base\nFROM base\nLABEL stage=final\n"}
+	body := s.build(c, files, types.ImageBuildOptions{Tags: []string{"target"}, Target: "base"})
+	c.Assert(body, Matches, `(?s).*Successfully built.*`)
+	s.server.mu.Lock()
+	image, _ := s.server.lookupImage("target")
+	s.server.mu.Unlock()
+	c.Assert(image.Config.Labels["stage"], Equals, "base")
+
+	body = s.build(c, files, types.ImageBuildOptions{Target: "missing"})
+	c.Assert(body, Matches, `(?s).*failed to reach build target missing in Dockerfile.*`)
+}
+
+func (s *ServerTest) TestBuildErrors(c *C) {
+	body := s.build(c, map[string]string{"Dockerfile": "FROM scratch\nRUN exit 3\n"}, types.ImageBuildOptions{})
+	c.Assert(body, Matches, `(?s).*"errorDetail":\{"message":"The command '/bin/sh -c exit 3' returned a non-zero code: 3"\}.*`)
+
+	body = s.build(c, map[string]string{"Dockerfile": "FROM scratch\nCOPY missing /\n"}, types.ImageBuildOptions{})
+	c.Assert(body, Matches, `(?s).*COPY failed: stat missing: no such file or directory.*`)
+
+	body = s.build(c, map[string]string{"Dockerfile": "FROM missing\n"}, types.ImageBuildOptions{})
+	c.Assert(body, Matches, `(?s).*pull access denied for missing.*`)
+
+	_, err := s.docker.ImageBuild(
+		context.Background(), buildContext(c, map[string]string{}), types.ImageBuildOptions{})
+	c.Assert(err, ErrorMatches, ".*Cannot locate specified Dockerfile: Dockerfile")
+}
@@ -0,0 +1,893 @@
+package fakeengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	"github.com/pkg/errors"
+)
+
+const timeNotSet = "0001-01-01T00:00:00Z"
+
+// ErrNoSuchContainer is returned by functions on Server which were given
+// a container which does not exist.
+var ErrNoSuchContainer = errors.New("no such container")
+
+type logEntry struct {
+	stream stdcopy.StdType
+	line   string
+	time   time.Time
+}
+
+type fakeContainer struct {
+	seq        int
+	id         string
+	name       string
+	imageRef   string
+	image      *Image
+	created    time.Time
+	config     *container.Config
+	hostConfig *container.HostConfig
+	state      types.ContainerState
+	ports      []types.Port
+	logs       []logEntry
+	files      map[string]*fileEntry
+	endpoints  map[string]*endpoint
+	mounts     []types.MountPoint
+	signals    []string
+}
+
+// createRequest mirrors the body sent by the docker client when
+// creating a container.
+type createRequest struct {
+	*container.Config
+	HostConfig       *container.HostConfig
+	NetworkingConfig *network.NetworkingConfig
+}
+
+func (c *fakeContainer) status() string {
+	switch c.state.Status {
+	case "running":
+		return "Up"
+	case "paused":
+		return "Up (Paused)"
+	case "exited":
+		return fmt.Sprintf("Exited (%d)", c.state.ExitCode)
+	default:
+		return "Created"
+	}
+}
+
+func (c *fakeContainer) summary() types.Container {
+	summary := types.Container{
+		ID:      c.id,
+		Names:   []string{"/" + c.name},
+		Image:   c.imageRef,
+		ImageID: c.image.ID,
+		Command: strings.Join(append(c.config.Entrypoint, c.config.Cmd...), " "),
+		Created: c.created.Unix(),
+		Ports:   c.ports,
+		Labels:  c.config.Labels,
+		State:   c.state.Status,
+		Status:  c.status(),
+		NetworkSettings: &types.SummaryNetworkSettings{
+			Networks: c.networkSettings(),
+		},
+		Mounts: c.mounts,
+	}
+	summary.HostConfig.NetworkMode = string(c.hostConfig.NetworkMode)
+	if summary.Ports == nil {
+		summary.Ports = []types.Port{}
+	}
+	return summary
+}
+
+func (c *fakeContainer) inspect() types.ContainerJSON {
+	state := c.state
+	ports := nat.PortMap{}
+	for _, port := range c.ports {
+		key := nat.Port(fmt.Sprintf("%d/%s", port.PrivatePort, port.Type))
+		if port.PublicPort == 0 {
+			ports[key] = nil
+			continue
+		}
+		ports[key] = append(ports[key], nat.PortBinding{
+			HostIP:   port.IP,
+			HostPort: strconv.Itoa(int(port.PublicPort)),
+		})
+	}
+
+	var args []string
+	command := append(append([]string{}, c.config.Entrypoint...), c.config.Cmd...)
+	path := ""
+	if len(command) > 0 {
+		path, args = command[0], command[1:]
+	}
+
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         c.id,
+			Created:    c.created.Format(time.RFC3339Nano),
+			Path:       path,
+			Args:       args,
+			State:      &state,
+			Image:      c.image.ID,
+			Name:       "/" + c.name,
+			HostConfig: c.hostConfig,
+		},
+		Mounts: c.mounts,
+		Config: c.config,
+		NetworkSettings: &types.NetworkSettings{
+			NetworkSettingsBase: types.NetworkSettingsBase{Ports: ports},
+			Networks:            c.networkSettings(),
+		},
+	}
+}
+
+// lookupContainer finds a container by id, unique id prefix or name. The
+// caller must hold s.mu.
+func (s *Server) lookupContainer(ref string) (*fakeContainer, bool) {
+	if c, ok := s.containers[ref]; ok {
+		return c, true
+	}
+	name := strings.TrimPrefix(ref, "/")
+	var found *fakeContainer
+	for _, c := range s.containers {
+		if c.name == name {
+			return c, true
+		}
+		if strings.HasPrefix(c.id, ref) {
+			if found != nil {
+				return nil, false
+			}
+			found = c
+		}
+	}
+	return found, found != nil
+}
+
+// sortedContainers returns all containers, newest first. The caller must
+// hold s.mu.
+func (s *Server) sortedContainers() []*fakeContainer {
+	containers := []*fakeContainer{}
+	for _, c := range s.containers {
+		containers = append(containers, c)
+	}
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].seq > containers[j].seq
+	})
+	return containers
+}
+
+func (s *Server) containerCreate(w http.ResponseWriter, r *http.Request, args []string) {
+	body := createRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	if body.Config == nil || body.Config.Image == "" {
+		writeError(w, http.StatusBadRequest, "No command specified")
+		return
+	}
+	if body.HostConfig == nil {
+		body.HostConfig = &container.HostConfig{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	image, ok := s.lookupImage(body.Config.Image)
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such image: %s", normalize(body.Config.Image))
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Query().Get("name"), "/")
+	if name != "" {
+		if existing, ok := s.lookupContainer(name); ok && existing.name == name {
+			writeError(w, http.StatusConflict,
+				`Conflict. The container name "/%s" is already in use by container "%s". You have to remove (or rename) that container to be able to reuse that name.`,
+				name, existing.id)
+			return
+		}
+	}
+
+	mode := string(body.HostConfig.NetworkMode)
+	if mode == "" || mode == "default" {
+		mode = "bridge"
+		body.HostConfig.NetworkMode = "default"
+	}
+	primary, ok := s.lookupNetwork(mode)
+	if !ok {
+		writeError(w, http.StatusNotFound, "network %s not found", mode)
+		return
+	}
+	var settings *network.EndpointSettings
+	if body.NetworkingConfig != nil {
+		if len(body.NetworkingConfig.EndpointsConfig) > 1 {
+			names := []string{}
+			for name := range body.NetworkingConfig.EndpointsConfig {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			writeError(w, http.StatusBadRequest,
+				"Container cannot be connected to network endpoints: %s", strings.Join(names, ", "))
+			return
+		}
+		for _, value := range body.NetworkingConfig.EndpointsConfig {
+			settings = value
+		}
+	}
+	if settings != nil && len(settings.Aliases) > 0 && primary.predefined {
+		writeError(w, http.StatusBadRequest,
+			"network-scoped alias is supported only for containers in user defined networks")
+		return
+	}
+
+	if err := validateResources(&body.HostConfig.Resources); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	mounts, err := s.mountPoints(body.HostConfig.Mounts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	id := s.nextID()
+	if name == "" {
+		name = fmt.Sprintf("fake_%d", s.counter)
+	}
+
+	c := &fakeContainer{
+		seq:        s.counter,
+		id:         id,
+		name:       name,
+		imageRef:   body.Config.Image,
+		image:      image,
+		created:    time.Now().UTC(),
+		config:     mergeConfig(&image.Config, body.Config),
+		hostConfig: body.HostConfig,
+		files:      newFilesystem(image),
+		endpoints:  map[string]*endpoint{},
+		mounts:     mounts,
+		state: types.ContainerState{
+			Status:     "created",
+			StartedAt:  timeNotSet,
+			FinishedAt: timeNotSet,
+		},
+	}
+	for _, point := range mounts {
+		if _, ok := c.files[point.Destination]; !ok {
+			writeFile(c.files, point.Destination, &fileEntry{mode: os.ModeDir | 0755, mtime: c.created})
+		}
+	}
+	if c.config.Hostname == "" {
+		c.config.Hostname = id[:12]
+	}
+	s.containers[id] = c
+	if primary.driver != "null" {
+		s.connect(c, primary, settings)
+	}
+	s.notify()
+
+	writeJSON(w, http.StatusCreated, container.ContainerCreateCreatedBody{ID: id, Warnings: []string{}})
+}
+
+// validateResources performs the same checks on memory limits as
+// Docker does when creating a container.
+func validateResources(resources *container.Resources) error {
+	if resources.Memory > 0 && resources.Memory < 4*1024*1024 {
+		return errors.New("Minimum memory limit allowed is 4MB")
+	}
+	if resources.MemorySwap > 0 && resources.Memory == 0 {
+		return errors.New("You should always set the Memory limit when using Memoryswap limit")
+	}
+	if resources.MemorySwap > 0 && resources.MemorySwap < resources.Memory {
+		return errors.New("Minimum memoryswap limit should be larger than memory limit, see usage")
+	}
+	return nil
+}
+
+// mergeConfig applies the defaults provided by the image to the requested
+// container configuration.
+func mergeConfig(image *container.Config, requested *container.Config) *container.Config {
+	config := *requested
+	config.Env = append(append([]string{}, image.Env...), requested.Env...)
+	config.Labels = map[string]string{}
+	for key, value := range image.Labels {
+		config.Labels[key] = value
+	}
+	for key, value := range requested.Labels {
+		config.Labels[key] = value
+	}
+	// As with Docker, the image's command is only used if the requested
+	// entrypoint is empty and an entrypoint of [""] clears the image's.
+	if len(config.Entrypoint) == 0 {
+		if len(config.Cmd) == 0 {
+			config.Cmd = image.Cmd
+		}
+		if config.Entrypoint == nil {
+			config.Entrypoint = image.Entrypoint
+		}
+	}
+	if len(config.Entrypoint) == 1 && config.Entrypoint[0] == "" {
+		config.Entrypoint = nil
+	}
+	if config.WorkingDir == "" {
+		config.WorkingDir = image.WorkingDir
+	}
+	if config.StopSignal == "" {
+		config.StopSignal = image.StopSignal
+	}
+	if config.Healthcheck == nil {
+		config.Healthcheck = image.Healthcheck
+	}
+	if config.User == "" {
+		config.User = image.User
+	}
+	if config.ExposedPorts == nil {
+		config.ExposedPorts = nat.PortSet{}
+	}
+	for port := range image.ExposedPorts {
+		config.ExposedPorts[port] = struct{}{}
+	}
+	return &config
+}
+
+func (s *Server) containerStart(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.lookupContainer(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", args[0])
+		return
+	}
+	if c.state.Running {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if err := s.start(c); err != nil {
+		s.notify()
+		writeError(w, http.StatusInternalServerError, "%s", err)
+		return
+	}
+	s.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// start runs a container which is not running, publishing its ports
+// again. The caller must hold s.mu.
+func (s *Server) start(c *fakeContainer) error {
+	if c.image.StartError != "" {
+		c.state.Error = c.image.StartError
+		c.state.ExitCode = 128
+		return errors.New(c.image.StartError)
+	}
+
+	ports, err := s.publish(c)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	c.ports = ports
+	c.state = types.ContainerState{
+		Status:     "running",
+		Running:    true,
+		Pid:        1000 + c.seq,
+		StartedAt:  now.Format(time.RFC3339Nano),
+		FinishedAt: timeNotSet,
+	}
+	for _, e := range c.endpoints {
+		s.allocate(e)
+	}
+	if healthcheckEnabled(c.config.Healthcheck) {
+		c.state.Health = &types.Health{Status: types.Starting}
+		go s.healthcheck(c, c.state.StartedAt)
+	}
+	for _, line := range c.image.Stdout {
+		c.logs = append(c.logs, logEntry{stream: stdcopy.Stdout, line: line, time: now})
+	}
+	for _, line := range c.image.Stderr {
+		c.logs = append(c.logs, logEntry{stream: stdcopy.Stderr, line: line, time: now})
+	}
+	if c.image.Exits {
+		s.exit(c, c.image.ExitCode)
+	}
+	return nil
+}
+
+// publish allocates host ports for the container's port bindings. The
+// caller must hold s.mu.
+func (s *Server) publish(c *fakeContainer) ([]types.Port, error) {
+	ports := []types.Port{}
+	seen := map[nat.Port]bool{}
+
+	keys := []string{}
+	for port := range c.hostConfig.PortBindings {
+		keys = append(keys, string(port))
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		port := nat.Port(key)
+		seen[port] = true
+		for _, binding := range c.hostConfig.PortBindings[port] {
+			public := binding.HostPort
+			if public == "" || public == "0" {
+				public = strconv.Itoa(int(s.nextPort))
+				s.nextPort++
+			}
+			value, err := strconv.ParseUint(public, 10, 16)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid host port %q", binding.HostPort)
+			}
+			address := binding.HostIP
+			if address == "" {
+				address = "0.0.0.0"
+			}
+			ports = append(ports, types.Port{
+				IP:          address,
+				PrivatePort: uint16(port.Int()),
+				PublicPort:  uint16(value),
+				Type:        port.Proto(),
+			})
+		}
+	}
+
+	for port := range c.config.ExposedPorts {
+		if !seen[port] {
+			ports = append(ports, types.Port{PrivatePort: uint16(port.Int()), Type: port.Proto()})
+		}
+	}
+	return ports, nil
+}
+
+// exit transitions a running container to the exited state. The caller
+// must hold s.mu.
+func (s *Server) exit(c *fakeContainer, code int) {
+	release(c)
+	c.ports = nil
+	c.state.Status = "exited"
+	c.state.Running = false
+	c.state.Paused = false
+	c.state.Pid = 0
+	c.state.ExitCode = code
+	c.state.FinishedAt = time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// SetCreated changes the time the requested container appears to have
+// been created at, allowing stale containers to be simulated.
+func (s *Server) SetCreated(id string, created time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		return ErrNoSuchContainer
+	}
+	c.created = created.UTC()
+	s.notify()
+	return nil
+}
+
+// Exit causes the requested running container to exit with the given
+// exit code.
+func (s *Server) Exit(id string, code int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		return ErrNoSuchContainer
+	}
+	if !c.state.Running {
+		return errors.Errorf("container %s is not running", id)
+	}
+	s.exit(c, code)
+	s.notify()
+	return nil
+}
+
+// OOMKill causes the requested running container to exit as if it had
+// been killed for running out of memory.
+func (s *Server) OOMKill(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		return ErrNoSuchContainer
+	}
+	if !c.state.Running {
+		return errors.Errorf("container %s is not running", id)
+	}
+	s.exit(c, 137)
+	c.state.OOMKilled = true
+	s.notify()
+	return nil
+}
+
+// SetHealth sets the health status, such as "healthy" or "unhealthy",
+// reported for the requested container.
+func (s *Server) SetHealth(id string, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		return ErrNoSuchContainer
+	}
+	if c.state.Health == nil {
+		c.state.Health = &types.Health{}
+	}
+	c.state.Health.Status = status
+	s.notify()
+	return nil
+}
+
+// WriteStdout appends a line to the container's stdout log.
+func (s *Server) WriteStdout(id string, line string) error {
+	return s.writeLog(id, stdcopy.Stdout, line)
+}
+
+// WriteStderr appends a line to the container's stderr log.
+func (s *Server) WriteStderr(id string, line string) error {
+	return s.writeLog(id, stdcopy.Stderr, line)
+}
+
+func (s *Server) writeLog(id string, stream stdcopy.StdType, line string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		return ErrNoSuchContainer
+	}
+	c.logs = append(c.logs, logEntry{stream: stream, line: line, time: time.Now().UTC()})
+	s.notify()
+	return nil
+}
+
+func (s *Server) containerInspect(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", args[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, c.inspect())
+}
+
+func (s *Server) containerList(w http.ResponseWriter, r *http.Request, args []string) {
+	query := r.URL.Query()
+	filter, err := filters.FromParam(query.Get("filters"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	if value := query.Get("since"); value != "" {
+		filter.Add("since", value)
+	}
+	if value := query.Get("before"); value != "" {
+		filter.Add("before", value)
+	}
+	all := query.Get("all") == "1" || query.Get("all") == "true"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matcher, err := s.newMatcher(filter)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	results := []types.Container{}
+	for _, c := range s.sortedContainers() {
+		if !all && !c.state.Running && !filter.Include("status") {
+			continue
+		}
+		if matcher.match(c) {
+			results = append(results, c.summary())
+		}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// matcher implements the container list filters.
+type matcher struct {
+	filter filters.Args
+	since  int
+	before int
+}
+
+func (s *Server) newMatcher(filter filters.Args) (*matcher, error) {
+	m := &matcher{filter: filter}
+	for key, target := range map[string]*int{"since": &m.since, "before": &m.before} {
+		for _, value := range filter.Get(key) {
+			c, ok := s.lookupContainer(value)
+			if !ok {
+				return nil, errors.Errorf("No such container: %s", value)
+			}
+			*target = c.seq
+		}
+	}
+	return m, nil
+}
+
+func (m *matcher) match(c *fakeContainer) bool {
+	if m.filter.Include("id") && !anyValue(m.filter.Get("id"), func(v string) bool {
+		return strings.HasPrefix(c.id, v)
+	}) {
+		return false
+	}
+	if m.filter.Include("name") && !m.filter.Match("name", c.name) {
+		return false
+	}
+	if !m.filter.MatchKVList("label", c.config.Labels) {
+		return false
+	}
+	if m.filter.Include("ancestor") && !anyValue(m.filter.Get("ancestor"), func(v string) bool {
+		return normalize(v) == normalize(c.imageRef) || v == c.image.ID
+	}) {
+		return false
+	}
+	if !m.filter.ExactMatch("status", c.state.Status) {
+		return false
+	}
+	if m.filter.Include("exited") && (c.state.Status != "exited" ||
+		!m.filter.ExactMatch("exited", strconv.Itoa(c.state.ExitCode))) {
+		return false
+	}
+	if m.filter.Include("network") && !anyValue(m.filter.Get("network"), func(v string) bool {
+		for _, e := range c.endpoints {
+			if e.network.name == v || strings.HasPrefix(e.network.id, v) {
+				return true
+			}
+		}
+		return false
+	}) {
+		return false
+	}
+	if m.filter.Include("health") {
+		health := types.NoHealthcheck
+		if c.state.Health != nil {
+			health = c.state.Health.Status
+		}
+		if !m.filter.ExactMatch("health", health) {
+			return false
+		}
+	}
+	if m.since != 0 && c.seq <= m.since {
+		return false
+	}
+	if m.before != 0 && c.seq >= m.before {
+		return false
+	}
+	return true
+}
+
+func anyValue(values []string, match func(string) bool) bool {
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) containerRemove(w http.ResponseWriter, r *http.Request, args []string) {
+	force := r.URL.Query().Get("force") == "1"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.lookupContainer(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", args[0])
+		return
+	}
+	if c.state.Running && !force {
+		writeError(w, http.StatusConflict,
+			"You cannot remove a running container %s. Stop the container before attempting removal or force remove", c.id)
+		return
+	}
+	if c.state.Running {
+		s.exit(c, 137)
+	}
+	delete(s.containers, c.id)
+	if r.URL.Query().Get("v") == "1" {
+		for _, point := range c.mounts {
+			if v, ok := s.volumes[point.Name]; ok && v.anonymous && len(s.users(v)) == 0 {
+				delete(s.volumes, v.name)
+			}
+		}
+	}
+	s.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) containerWait(w http.ResponseWriter, r *http.Request, args []string) {
+	condition := container.WaitCondition(r.URL.Query().Get("condition"))
+	switch condition {
+	case "":
+		condition = container.WaitConditionNotRunning
+	case container.WaitConditionNotRunning, container.WaitConditionNextExit, container.WaitConditionRemoved:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid condition: %q", condition)
+		return
+	}
+
+	s.mu.Lock()
+	c, ok := s.lookupContainer(args[0])
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "No such container: %s", args[0])
+		return
+	}
+	finished := c.state.FinishedAt
+	s.mu.Unlock()
+
+	// The client waits for the headers before returning from
+	// ContainerWait so they are sent before waiting.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+
+	for {
+		s.mu.Lock()
+		removed := s.containers[c.id] != c
+		done := removed
+		switch condition {
+		case container.WaitConditionNotRunning:
+			done = done || !c.state.Running
+		case container.WaitConditionNextExit:
+			done = done || (!c.state.Running && c.state.FinishedAt != finished)
+		}
+		code := c.state.ExitCode
+		changed := s.changed
+		s.mu.Unlock()
+
+		if done {
+			json.NewEncoder(w).Encode(container.ContainerWaitOKBody{StatusCode: int64(code)}) // nolint: errcheck
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) containerLogs(w http.ResponseWriter, r *http.Request, args []string) {
+	query := r.URL.Query()
+	stdout := query.Get("stdout") == "1"
+	stderr := query.Get("stderr") == "1"
+	if !stdout && !stderr {
+		writeError(w, http.StatusBadRequest, "Bad parameters: you must choose at least one stream")
+		return
+	}
+
+	since, err := parseTimestamp(query.Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	until, err := parseTimestamp(query.Get("until"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	s.mu.Lock()
+	c, ok := s.lookupContainer(args[0])
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "No such container: %s", args[0])
+		return
+	}
+	tty := c.config.Tty
+	entries := append([]logEntry{}, c.logs...)
+	s.mu.Unlock()
+
+	selected := []logEntry{}
+	for _, entry := range entries {
+		if (entry.stream == stdcopy.Stdout && !stdout) || (entry.stream == stdcopy.Stderr && !stderr) {
+			continue
+		}
+		if !since.IsZero() && entry.time.Before(since) {
+			continue
+		}
+		if !until.IsZero() && entry.time.After(until) {
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	if tail, err := strconv.Atoi(query.Get("tail")); err == nil && tail >= 0 && tail < len(selected) {
+		selected = selected[len(selected)-tail:]
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+	w.WriteHeader(http.StatusOK)
+	timestamps := query.Get("timestamps") == "1"
+	write := func(entry logEntry) {
+		line := entry.line + "\n"
+		if timestamps {
+			line = entry.time.Format(time.RFC3339Nano) + " " + line
+		}
+		var out io.Writer = w
+		if !tty {
+			out = stdcopy.NewStdWriter(w, entry.stream)
+		}
+		io.WriteString(out, line) // nolint: errcheck
+	}
+	for _, entry := range selected {
+		write(entry)
+	}
+	flush(w)
+
+	if query.Get("follow") != "1" || !until.IsZero() {
+		return
+	}
+
+	sent := len(entries)
+	for {
+		s.mu.Lock()
+		current, exists := s.containers[c.id]
+		pending := append([]logEntry{}, c.logs[sent:]...)
+		sent = len(c.logs)
+		running := c.state.Running
+		changed := s.changed
+		s.mu.Unlock()
+
+		for _, entry := range pending {
+			if (entry.stream == stdcopy.Stdout && stdout) || (entry.stream == stdcopy.Stderr && stderr) {
+				write(entry)
+			}
+		}
+		flush(w)
+
+		if !exists || current != c || !running {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// parseTimestamp parses the seconds[.nanoseconds] format used by the
+// logs endpoint.
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" || value == "0" {
+		return time.Time{}, nil
+	}
+	parts := strings.SplitN(value, ".", 2)
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "invalid timestamp %q", value)
+	}
+	var nanoseconds int64
+	if len(parts) == 2 {
+		nanoseconds, err = strconv.ParseInt((parts[1] + "000000000")[:9], 10, 64)
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "invalid timestamp %q", value)
+		}
+	}
+	return time.Unix(seconds, nanoseconds), nil
+}
@@ -0,0 +1,222 @@
+package fakeengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Exec describes a command run inside of a container.
+type Exec struct {
+	ContainerID string
+	Cmd         []string
+	Env         []string
+	User        string
+	Tty         bool
+
+	// Stdin is empty unless the client attached stdin. Stdout and
+	// Stderr write to the attached client.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ExecHandler runs an exec and returns its exit code.
+type ExecHandler func(*Exec) int
+
+// builtinExec implements a handful of common commands and is used for
+// images which do not provide their own ExecHandler.
+func builtinExec(exec *Exec) int {
+	if len(exec.Cmd) == 0 {
+		return 126
+	}
+	if len(exec.Cmd) == 3 && (exec.Cmd[0] == "/bin/sh" || exec.Cmd[0] == "sh") && exec.Cmd[1] == "-c" {
+		shell := *exec
+		shell.Cmd = strings.Fields(exec.Cmd[2])
+		return builtinExec(&shell)
+	}
+	switch exec.Cmd[0] {
+	case "true":
+		return 0
+	case "false":
+		return 1
+	case "echo":
+		fmt.Fprintln(exec.Stdout, strings.Join(exec.Cmd[1:], " ")) // nolint: errcheck
+		return 0
+	case "cat":
+		io.Copy(exec.Stdout, exec.Stdin) // nolint: errcheck
+		return 0
+	case "env":
+		for _, value := range exec.Env {
+			fmt.Fprintln(exec.Stdout, value) // nolint: errcheck
+		}
+		return 0
+	case "whoami":
+		user := exec.User
+		if user == "" {
+			user = "root"
+		}
+		fmt.Fprintln(exec.Stdout, user) // nolint: errcheck
+		return 0
+	}
+	fmt.Fprintf( // nolint: errcheck
+		exec.Stderr, "OCI runtime exec failed: exec failed: %q: executable file not found in $PATH\n", exec.Cmd[0])
+	return 126
+}
+
+type fakeExec struct {
+	id        string
+	container *fakeContainer
+	config    types.ExecConfig
+	started   bool
+	running   bool
+	exitCode  int
+	pid       int
+}
+
+func (s *Server) execCreate(w http.ResponseWriter, r *http.Request, args []string) {
+	config := types.ExecConfig{}
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", args[0])
+		return
+	}
+	if !c.state.Running {
+		writeError(w, http.StatusConflict, "Container %s is not running", c.id)
+		return
+	}
+	if c.state.Paused {
+		writeError(w, http.StatusConflict, "Container %s is paused, unpause the container before exec", c.id)
+		return
+	}
+	if len(config.Cmd) == 0 {
+		writeError(w, http.StatusBadRequest, "No exec command specified")
+		return
+	}
+
+	exec := &fakeExec{id: s.nextID(), container: c, config: config}
+	s.execs[exec.id] = exec
+	writeJSON(w, http.StatusCreated, types.IDResponse{ID: exec.id})
+}
+
+func (s *Server) execStart(w http.ResponseWriter, r *http.Request, args []string) {
+	check := types.ExecStartCheck{}
+	if err := json.NewDecoder(r.Body).Decode(&check); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	s.mu.Lock()
+	exec, ok := s.execs[args[0]]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "No such exec instance: %s", args[0])
+		return
+	}
+	if exec.started {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "Error: Exec command %s has already run", exec.id)
+		return
+	}
+	if !exec.container.state.Running {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "Container %s is not running", exec.container.id)
+		return
+	}
+	exec.started = true
+	exec.running = true
+	exec.pid = exec.container.state.Pid + len(s.execs)
+	run := exec.container.image.Exec
+	if run == nil {
+		run = builtinExec
+	}
+	env := append(append([]string{}, exec.container.config.Env...), exec.config.Env...)
+	user := exec.config.User
+	if user == "" {
+		user = exec.container.config.User
+	}
+	s.mu.Unlock()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "connection does not support hijacking")
+		return
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%s", err)
+		return
+	}
+
+	contentType := "application/vnd.docker.multiplexed-stream"
+	if exec.config.Tty {
+		contentType = "application/vnd.docker.raw-stream"
+	}
+	fmt.Fprintf(buffered, "HTTP/1.1 101 UPGRADED\r\nContent-Type: %s\r\n"+ // nolint: errcheck
+		"Connection: Upgrade\r\nUpgrade: tcp\r\n\r\n", contentType)
+	buffered.Flush() // nolint: errcheck
+
+	var stdin io.Reader = strings.NewReader("")
+	if exec.config.AttachStdin {
+		stdin = buffered
+	}
+	stdout, stderr := io.Writer(conn), io.Writer(conn)
+	if !exec.config.Tty {
+		stdout = stdcopy.NewStdWriter(conn, stdcopy.Stdout)
+		stderr = stdcopy.NewStdWriter(conn, stdcopy.Stderr)
+	}
+	if !exec.config.AttachStdout {
+		stdout = ioutil.Discard
+	}
+	if !exec.config.AttachStderr {
+		stderr = ioutil.Discard
+	}
+
+	code := run(&Exec{
+		ContainerID: exec.container.id,
+		Cmd:         exec.config.Cmd,
+		Env:         env,
+		User:        user,
+		Tty:         exec.config.Tty,
+		Stdin:       stdin,
+		Stdout:      stdout,
+		Stderr:      stderr,
+	})
+
+	s.mu.Lock()
+	exec.running = false
+	exec.exitCode = code
+	s.notify()
+	s.mu.Unlock()
+	conn.Close() // nolint: errcheck
+}
+
+func (s *Server) execInspect(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exec, ok := s.execs[args[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such exec instance: %s", args[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, types.ContainerExecInspect{
+		ExecID:      exec.id,
+		ContainerID: exec.container.id,
+		Running:     exec.running,
+		ExitCode:    exec.exitCode,
+		Pid:         exec.pid,
+	})
+}
@@ -0,0 +1,85 @@
+package fakeengine
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	. "gopkg.in/check.v1"
+)
+
+func (s *ServerTest) exec(c *C, id string, config types.ExecConfig) (string, string, int) {
+	created, err := s.docker.ContainerExecCreate(context.Background(), id, config)
+	c.Assert(err, IsNil)
+	attached, err := s.docker.ContainerExecAttach(context.Background(), created.ID, config)
+	c.Assert(err, IsNil)
+	defer attached.Close()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	_, err = stdcopy.StdCopy(stdout, stderr, attached.Reader)
+	c.Assert(err, IsNil)
+	inspection, err := s.docker.ContainerExecInspect(context.Background(), created.ID)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.Running, Equals, false)
+	return stdout.String(), stderr.String(), inspection.ExitCode
+}
+
+func (s *ServerTest) TestExec(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.create(c, "test", nil)
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+
+	stdout, stderr, code := s.exec(c, id, types.ExecConfig{
+		Cmd: []string{"echo", "hello", "world"}, AttachStdout: true, AttachStderr: true,
+	})
+	c.Assert(stdout, Equals, "hello world\n")
+	c.Assert(stderr, Equals, "")
+	c.Assert(code, Equals, 0)
+
+	stdout, _, _ = s.exec(c, id, types.ExecConfig{
+		Cmd: []string{"whoami"}, User: "nobody", AttachStdout: true,
+	})
+	c.Assert(stdout, Equals, "nobody\n")
+
+	_, stderr, code = s.exec(c, id, types.ExecConfig{
+		Cmd: []string{"missing"}, AttachStdout: true, AttachStderr: true,
+	})
+	c.Assert(stderr, Matches, ".*executable file not found.*\n")
+	c.Assert(code, Equals, 126)
+
+	_, _, code = s.exec(c, id, types.ExecConfig{Cmd: []string{"false"}})
+	c.Assert(code, Equals, 1)
+}
+
+func (s *ServerTest) TestExecStdin(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.create(c, "test", nil)
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+
+	config := types.ExecConfig{Cmd: []string{"cat"}, AttachStdin: true, AttachStdout: true}
+	created, err := s.docker.ContainerExecCreate(context.Background(), id, config)
+	c.Assert(err, IsNil)
+	attached, err := s.docker.ContainerExecAttach(context.Background(), created.ID, config)
+	c.Assert(err, IsNil)
+	defer attached.Close()
+
+	_, err = attached.Conn.Write([]byte("input"))
+	c.Assert(err, IsNil)
+	c.Assert(attached.CloseWrite(), IsNil)
+	stdout := &bytes.Buffer{}
+	_, err = stdcopy.StdCopy(stdout, ioutil.Discard, attached.Reader)
+	c.Assert(err, IsNil)
+	c.Assert(stdout.String(), Equals, "input")
+}
+
+func (s *ServerTest) TestExecNotRunning(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.create(c, "test", nil)
+	_, err := s.docker.ContainerExecCreate(context.Background(), id, types.ExecConfig{Cmd: []string{"true"}})
+	c.Assert(err, ErrorMatches, ".*is not running")
+
+	_, err = s.docker.ContainerExecInspect(context.Background(), "missing")
+	c.Assert(err, ErrorMatches, ".*No such exec instance.*")
+}
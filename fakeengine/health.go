@@ -0,0 +1,206 @@
+package fakeengine
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+// The defaults Docker uses for healthchecks which do not set a value.
+const (
+	defaultProbeInterval = time.Second * 30
+	defaultProbeTimeout  = time.Second * 30
+	defaultProbeRetries  = 3
+
+	// maxHealthLog is the number of probe results Docker keeps.
+	maxHealthLog = 5
+
+	// maxProbeOutput is the number of bytes of probe output Docker keeps.
+	maxProbeOutput = 4096
+)
+
+// healthcheckEnabled returns true if config defines a healthcheck which
+// has not been disabled.
+func healthcheckEnabled(config *container.HealthConfig) bool {
+	return config != nil && len(config.Test) > 0 && config.Test[0] != "NONE"
+}
+
+// probeCommand converts the Test of a healthcheck into the command to
+// run inside of the container.
+func probeCommand(test []string) []string {
+	switch test[0] {
+	case "CMD":
+		return test[1:]
+	case "CMD-SHELL":
+		return []string{"/bin/sh", "-c", strings.Join(test[1:], " ")}
+	}
+	return test
+}
+
+// parseHealthcheck parses the arguments of a HEALTHCHECK instruction.
+func parseHealthcheck(value string) (*container.HealthConfig, error) {
+	config := &container.HealthConfig{}
+	fields := strings.Fields(value)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+		parts := strings.SplitN(strings.TrimPrefix(fields[0], "--"), "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("missing value for HEALTHCHECK option %s", fields[0])
+		}
+		var err error
+		switch parts[0] {
+		case "interval":
+			config.Interval, err = time.ParseDuration(parts[1])
+		case "timeout":
+			config.Timeout, err = time.ParseDuration(parts[1])
+		case "start-period":
+			config.StartPeriod, err = time.ParseDuration(parts[1])
+		case "retries":
+			config.Retries, err = strconv.Atoi(parts[1])
+		default:
+			return nil, errors.Errorf("unknown flag: %s", parts[0])
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid HEALTHCHECK option %s", fields[0])
+		}
+		fields = fields[1:]
+	}
+
+	if len(fields) == 1 && strings.ToUpper(fields[0]) == "NONE" {
+		config.Test = []string{"NONE"}
+		return config, nil
+	}
+	if len(fields) < 2 || strings.ToUpper(fields[0]) != "CMD" {
+		return nil, errors.New("HEALTHCHECK requires CMD or NONE")
+	}
+	command := strings.TrimSpace(strings.SplitN(strings.Join(fields, " "), " ", 2)[1])
+	parsed := parseCommand(command)
+	if len(parsed) == 3 && parsed[0] == "/bin/sh" && parsed[1] == "-c" {
+		config.Test = []string{"CMD-SHELL", parsed[2]}
+	} else {
+		config.Test = append([]string{"CMD"}, parsed...)
+	}
+	return config, nil
+}
+
+// healthcheck probes a container which was started at started until it
+// stops, is restarted or is removed. The caller must not hold s.mu.
+func (s *Server) healthcheck(c *fakeContainer, started string) {
+	config := c.config.Healthcheck
+	interval, timeout, retries := config.Interval, config.Timeout, config.Retries
+	if interval == 0 {
+		interval = defaultProbeInterval
+	}
+	if timeout == 0 {
+		timeout = defaultProbeTimeout
+	}
+	if retries == 0 {
+		retries = defaultProbeRetries
+	}
+	startedAt := time.Now()
+
+	for {
+		select {
+		case <-time.After(interval):
+		case <-s.closed:
+			return
+		}
+
+		s.mu.Lock()
+		if s.containers[c.id] != c || !c.state.Running || c.state.StartedAt != started {
+			s.mu.Unlock()
+			return
+		}
+		if c.state.Paused {
+			s.mu.Unlock()
+			continue
+		}
+		run := c.image.Exec
+		if run == nil {
+			run = builtinExec
+		}
+		exec := &Exec{
+			ContainerID: c.id,
+			Cmd:         probeCommand(config.Test),
+			Env:         append([]string{}, c.config.Env...),
+			User:        c.config.User,
+			Stdin:       strings.NewReader(""),
+		}
+		s.mu.Unlock()
+
+		result := s.probe(run, exec, timeout)
+
+		s.mu.Lock()
+		if s.containers[c.id] != c || c.state.StartedAt != started || c.state.Health == nil {
+			s.mu.Unlock()
+			return
+		}
+		health := c.state.Health
+		health.Log = append(health.Log, result)
+		if len(health.Log) > maxHealthLog {
+			health.Log = health.Log[len(health.Log)-maxHealthLog:]
+		}
+		switch {
+		case result.ExitCode == 0:
+			health.Status = types.Healthy
+			health.FailingStreak = 0
+		case health.Status == types.Starting && time.Since(startedAt) < config.StartPeriod:
+			// Failures during the start period do not count.
+		default:
+			health.FailingStreak++
+			if health.FailingStreak >= retries {
+				health.Status = types.Unhealthy
+			}
+		}
+		s.notify()
+		s.mu.Unlock()
+	}
+}
+
+// probe runs a single healthcheck command, giving up after timeout.
+func (s *Server) probe(run ExecHandler, exec *Exec, timeout time.Duration) *types.HealthcheckResult {
+	output := &syncBuffer{}
+	exec.Stdout, exec.Stderr = output, output
+	result := &types.HealthcheckResult{Start: time.Now().UTC()}
+	codes := make(chan int, 1)
+	go func() {
+		codes <- run(exec)
+	}()
+	select {
+	case code := <-codes:
+		result.ExitCode = code
+		result.Output = output.String()
+	case <-time.After(timeout):
+		result.ExitCode = -1
+		result.Output = "Health check exceeded timeout (" + timeout.String() + ")"
+	}
+	if len(result.Output) > maxProbeOutput {
+		result.Output = result.Output[:maxProbeOutput]
+	}
+	result.End = time.Now().UTC()
+	return result
+}
+
+// syncBuffer is a bytes.Buffer which may be written to by a probe which
+// is still running after it timed out.
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(data)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.String()
+}
@@ -0,0 +1,149 @@
+package fakeengine
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	. "gopkg.in/check.v1"
+)
+
+func (s *ServerTest) TestParseHealthcheck(c *C) {
+	config, err := parseHealthcheck("--interval=5s --timeout=1s --start-period=10s --retries=2 CMD curl -f http://localhost/")
+	c.Assert(err, IsNil)
+	c.Assert(config, DeepEquals, &container.HealthConfig{
+		Test:        []string{"CMD-SHELL", "curl -f http://localhost/"},
+		Interval:    time.Second * 5,
+		Timeout:     time.Second,
+		StartPeriod: time.Second * 10,
+		Retries:     2,
+	})
+
+	config, err = parseHealthcheck(`CMD ["pg_isready", "-U", "postgres"]`)
+	c.Assert(err, IsNil)
+	c.Assert(config.Test, DeepEquals, []string{"CMD", "pg_isready", "-U", "postgres"})
+
+	config, err = parseHealthcheck("NONE")
+	c.Assert(err, IsNil)
+	c.Assert(config.Test, DeepEquals, []string{"NONE"})
+
+	_, err = parseHealthcheck("--interval=soon CMD true")
+	c.Assert(err, ErrorMatches, "invalid HEALTHCHECK option --interval=soon.*")
+	_, err = parseHealthcheck("--every=1s CMD true")
+	c.Assert(err, ErrorMatches, "unknown flag: every")
+	_, err = parseHealthcheck("true")
+	c.Assert(err, ErrorMatches, "HEALTHCHECK requires CMD or NONE")
+}
+
+func (s *ServerTest) TestBuildHealthcheck(c *C) {
+	s.build(c, map[string]string{
+		"Dockerfile": "FROM scratch\nHEALTHCHECK --interval=10ms CMD true\n",
+	}, types.ImageBuildOptions{Tags: []string{"healthy"}})
+	s.server.mu.Lock()
+	image, ok := s.server.lookupImage("healthy")
+	s.server.mu.Unlock()
+	c.Assert(ok, Equals, true)
+	c.Assert(image.Config.Healthcheck, DeepEquals, &container.HealthConfig{
+		Test: []string{"CMD-SHELL", "true"}, Interval: time.Millisecond * 10,
+	})
+}
+
+// createHealthcheck creates and starts a container with the provided
+// healthcheck.
+func (s *ServerTest) createHealthcheck(c *C, image string, healthcheck *container.HealthConfig) string {
+	created, err := s.docker.ContainerCreate(context.Background(),
+		&container.Config{Image: image, Healthcheck: healthcheck},
+		&container.HostConfig{}, &network.NetworkingConfig{}, "")
+	c.Assert(err, IsNil)
+	c.Assert(s.docker.ContainerStart(context.Background(), created.ID, types.ContainerStartOptions{}), IsNil)
+	return created.ID
+}
+
+// waitForHealth waits for the container to reach the requested status.
+func (s *ServerTest) waitForHealth(c *C, id string, status string) *types.Health {
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		inspection := s.inspect(c, id)
+		if inspection.State.Health != nil && inspection.State.Health.Status == status {
+			return inspection.State.Health
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+	c.Fatalf("container did not become %s", status)
+	return nil
+}
+
+func (s *ServerTest) TestHealthcheck(c *C) {
+	var fail int32
+	s.server.AddImage("test", &Image{
+		Exec: func(exec *Exec) int {
+			if atomic.LoadInt32(&fail) == 1 {
+				fmt.Fprintln(exec.Stdout, "down") // nolint: errcheck
+				return 1
+			}
+			fmt.Fprintln(exec.Stdout, "up") // nolint: errcheck
+			return 0
+		},
+	})
+	id := s.createHealthcheck(c, "test", &container.HealthConfig{
+		Test: []string{"CMD", "check"}, Interval: time.Millisecond * 10, Retries: 2,
+	})
+	c.Assert(s.inspect(c, id).State.Health.Status, Equals, types.Starting)
+
+	health := s.waitForHealth(c, id, types.Healthy)
+	c.Assert(health.Log[0].Output, Equals, "up\n")
+	c.Assert(health.Log[0].ExitCode, Equals, 0)
+
+	atomic.StoreInt32(&fail, 1)
+	health = s.waitForHealth(c, id, types.Unhealthy)
+	c.Assert(health.FailingStreak >= 2, Equals, true)
+	c.Assert(len(health.Log) <= maxHealthLog, Equals, true)
+	c.Assert(health.Log[len(health.Log)-1].Output, Equals, "down\n")
+
+	atomic.StoreInt32(&fail, 0)
+	s.waitForHealth(c, id, types.Healthy)
+}
+
+func (s *ServerTest) TestHealthcheckFromImage(c *C) {
+	s.server.AddImage("test", &Image{Config: container.Config{Healthcheck: &container.HealthConfig{
+		Test: []string{"CMD-SHELL", "false"}, Interval: time.Millisecond * 10, Retries: 1,
+	}}})
+	id := s.createHealthcheck(c, "test", nil)
+	s.waitForHealth(c, id, types.Unhealthy)
+
+	// A healthcheck of NONE disables the image's healthcheck.
+	id = s.createHealthcheck(c, "test", &container.HealthConfig{Test: []string{"NONE"}})
+	time.Sleep(time.Millisecond * 30)
+	c.Assert(s.inspect(c, id).State.Health, IsNil)
+}
+
+func (s *ServerTest) TestHealthcheckStartPeriod(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.createHealthcheck(c, "test", &container.HealthConfig{
+		Test: []string{"CMD", "false"}, Interval: time.Millisecond * 10, Retries: 1, StartPeriod: time.Hour,
+	})
+	time.Sleep(time.Millisecond * 50)
+	health := s.inspect(c, id).State.Health
+	c.Assert(health.Status, Equals, types.Starting)
+	c.Assert(health.FailingStreak, Equals, 0)
+	c.Assert(len(health.Log) > 0, Equals, true)
+}
+
+func (s *ServerTest) TestHealthcheckTimeout(c *C) {
+	s.server.AddImage("test", &Image{
+		Exec: func(exec *Exec) int {
+			time.Sleep(time.Millisecond * 100)
+			return 0
+		},
+	})
+	id := s.createHealthcheck(c, "test", &container.HealthConfig{
+		Test: []string{"CMD", "slow"}, Interval: time.Millisecond * 10, Timeout: time.Millisecond, Retries: 1,
+	})
+	health := s.waitForHealth(c, id, types.Unhealthy)
+	c.Assert(health.Log[0].ExitCode, Equals, -1)
+	c.Assert(health.Log[0].Output, Equals, "Health check exceeded timeout (1ms)")
+}
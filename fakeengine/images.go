@@ -0,0 +1,201 @@
+package fakeengine
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+// Image describes an image known to the Server along with the behavior
+// of containers created from it.
+type Image struct {
+	// ID is the image id. One will be generated from the reference if
+	// not provided.
+	ID string
+
+	// Config provides defaults, such as Env, Cmd and Labels, for
+	// containers created from this image.
+	Config container.Config
+
+	// Files maps paths to the content of files which exist inside of
+	// containers created from this image.
+	Files map[string]string
+
+	// Stdout and Stderr are lines written to the container's log
+	// when it starts.
+	Stdout []string
+	Stderr []string
+
+	// Exits causes containers to exit with ExitCode immediately after
+	// they have started.
+	Exits    bool
+	ExitCode int
+
+	// HandledSignals lists the signals, such as SIGHUP, which the
+	// process in containers created from this image handles without
+	// exiting. Other signals cause the container to exit with 128 plus
+	// the signal number. If the stop signal is handled stopping the
+	// container falls back to SIGKILL.
+	HandledSignals []string
+
+	// StartError, if set, causes starting a container to fail with
+	// this message.
+	StartError string
+
+	// PullError, if set, is reported inside of the pull progress stream
+	// when this image is pulled. The image will not be pulled.
+	PullError string
+
+	// Exec, if set, runs commands executed inside of containers created
+	// from this image. A few common commands such as echo, cat and env
+	// are implemented when it is not set.
+	Exec ExecHandler
+
+	// Username and Password, if set, must be provided as registry
+	// credentials in order to pull this image.
+	Username string
+	Password string
+}
+
+// normalize converts ref into the familiar form used as the key for
+// images, for example nginx becomes nginx:latest.
+func normalize(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ref
+	}
+	return reference.FamiliarString(reference.TagNameOnly(named))
+}
+
+func imageID(ref string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(ref)))
+}
+
+// AddImage adds an image which is available locally, as if it had
+// already been pulled.
+func (s *Server) AddImage(ref string, image *Image) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if image.ID == "" {
+		image.ID = imageID(normalize(ref))
+	}
+	s.images[normalize(ref)] = image
+}
+
+// AddRemoteImage adds an image which is not available locally but may be
+// pulled from the fake registry.
+func (s *Server) AddRemoteImage(ref string, image *Image) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if image.ID == "" {
+		image.ID = imageID(normalize(ref))
+	}
+	s.registry[normalize(ref)] = image
+}
+
+// HasImage returns true if ref is available locally.
+func (s *Server) HasImage(ref string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.lookupImage(ref)
+	return ok
+}
+
+// lookupImage finds a local image by reference or id. The caller must
+// hold s.mu.
+func (s *Server) lookupImage(ref string) (*Image, bool) {
+	if image, ok := s.images[normalize(ref)]; ok {
+		return image, true
+	}
+	for _, image := range s.images {
+		if image.ID == ref || strings.TrimPrefix(image.ID, "sha256:") == ref {
+			return image, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) imageCreate(w http.ResponseWriter, r *http.Request, args []string) {
+	name := r.URL.Query().Get("fromImage")
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		name = name + ":" + tag
+	}
+	ref := normalize(name)
+
+	auth, err := decodeAuth(r.Header.Get("X-Registry-Auth"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	s.mu.Lock()
+	image, ok := s.registry[ref]
+	s.auths = append(s.auths, auth)
+	s.mu.Unlock()
+	if !ok {
+		repository := strings.Split(ref, ":")[0]
+		writeError(w, http.StatusNotFound,
+			"pull access denied for %s, repository does not exist or may require 'docker login'", repository)
+		return
+	}
+
+	if image.Username != "" && (auth.Username != image.Username || auth.Password != image.Password) {
+		if auth.Username == "" {
+			writeError(w, http.StatusInternalServerError, "unauthorized: authentication required")
+		} else {
+			writeError(w, http.StatusInternalServerError, "unauthorized: incorrect username or password")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.Encode(map[string]string{"status": "Pulling from " + ref}) // nolint: errcheck
+	flush(w)
+
+	if image.PullError != "" {
+		encoder.Encode(map[string]interface{}{ // nolint: errcheck
+			"errorDetail": map[string]string{"message": image.PullError},
+			"error":       image.PullError,
+		})
+		return
+	}
+
+	s.mu.Lock()
+	s.images[ref] = image
+	s.mu.Unlock()
+	encoder.Encode(map[string]string{"status": "Digest: " + image.ID})                       // nolint: errcheck
+	encoder.Encode(map[string]string{"status": "Status: Downloaded newer image for " + ref}) // nolint: errcheck
+}
+
+// decodeAuth decodes the X-Registry-Auth header.
+func decodeAuth(header string) (types.AuthConfig, error) {
+	auth := types.AuthConfig{}
+	if header == "" {
+		return auth, nil
+	}
+	data, err := base64.URLEncoding.DecodeString(header)
+	if err != nil {
+		return auth, errors.Wrap(err, "invalid X-Registry-Auth header")
+	}
+	if err := json.Unmarshal(data, &auth); err != nil {
+		return auth, errors.Wrap(err, "invalid X-Registry-Auth header")
+	}
+	return auth, nil
+}
+
+// RegistryAuths returns the registry credentials which were sent with
+// each pull request, in order.
+func (s *Server) RegistryAuths() []types.AuthConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]types.AuthConfig{}, s.auths...)
+}
@@ -0,0 +1,180 @@
+package fakeengine
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// signals maps the names of the signals accepted by kill and stop to
+// their numbers on Linux.
+var signals = map[string]int{
+	"SIGHUP": 1, "SIGINT": 2, "SIGQUIT": 3, "SIGILL": 4, "SIGTRAP": 5,
+	"SIGABRT": 6, "SIGBUS": 7, "SIGFPE": 8, "SIGKILL": 9, "SIGUSR1": 10,
+	"SIGSEGV": 11, "SIGUSR2": 12, "SIGPIPE": 13, "SIGALRM": 14, "SIGTERM": 15,
+	"SIGCHLD": 17, "SIGCONT": 18, "SIGSTOP": 19, "SIGTSTP": 20, "SIGWINCH": 28,
+}
+
+// parseSignal converts a signal name, with or without the SIG prefix,
+// or number into the signal's name and number.
+func parseSignal(value string) (string, int, error) {
+	if number, err := strconv.Atoi(value); err == nil {
+		for name, n := range signals {
+			if n == number {
+				return name, number, nil
+			}
+		}
+		return "", 0, errors.Errorf("Invalid signal: %s", value)
+	}
+	name := strings.ToUpper(value)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	number, ok := signals[name]
+	if !ok {
+		return "", 0, errors.Errorf("Invalid signal: %s", value)
+	}
+	return name, number, nil
+}
+
+// signal delivers a signal to a running container. The container exits
+// with 128 plus the signal number unless its image handles the signal.
+// The caller must hold s.mu.
+func (s *Server) signal(c *fakeContainer, name string, number int) {
+	c.signals = append(c.signals, name)
+	if name != "SIGKILL" {
+		for _, handled := range c.image.HandledSignals {
+			if handled, _, err := parseSignal(handled); err == nil && handled == name {
+				return
+			}
+		}
+	}
+	s.exit(c, 128+number)
+}
+
+// stop delivers the container's stop signal, killing the container if
+// its image ignores the signal. The caller must hold s.mu.
+func (s *Server) stop(c *fakeContainer) {
+	name, number, err := parseSignal(c.config.StopSignal)
+	if c.config.StopSignal == "" || err != nil {
+		name, number = "SIGTERM", signals["SIGTERM"]
+	}
+	s.signal(c, name, number)
+	if c.state.Running {
+		s.signal(c, "SIGKILL", signals["SIGKILL"])
+	}
+}
+
+// Signals returns the signals which have been sent to the requested
+// container, in the order they were sent.
+func (s *Server) Signals(id string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		return nil, ErrNoSuchContainer
+	}
+	return append([]string{}, c.signals...), nil
+}
+
+func (s *Server) containerStop(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", args[0])
+		return
+	}
+	if !c.state.Running {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.stop(c)
+	s.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) containerKill(w http.ResponseWriter, r *http.Request, args []string) {
+	value := r.URL.Query().Get("signal")
+	if value == "" {
+		value = "SIGKILL"
+	}
+	name, number, err := parseSignal(value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", args[0])
+		return
+	}
+	if !c.state.Running {
+		writeError(w, http.StatusConflict, "Container %s is not running", c.id)
+		return
+	}
+	s.signal(c, name, number)
+	s.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) containerPause(w http.ResponseWriter, r *http.Request, args []string) {
+	s.setPaused(w, args[0], true)
+}
+
+func (s *Server) containerUnpause(w http.ResponseWriter, r *http.Request, args []string) {
+	s.setPaused(w, args[0], false)
+}
+
+func (s *Server) setPaused(w http.ResponseWriter, id string, paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", id)
+		return
+	}
+	switch {
+	case !c.state.Running:
+		writeError(w, http.StatusConflict, "Container %s is not running", c.id)
+		return
+	case paused && c.state.Paused:
+		writeError(w, http.StatusConflict, "Container %s is already paused", c.id)
+		return
+	case !paused && !c.state.Paused:
+		writeError(w, http.StatusConflict, "Container %s is not paused", c.id)
+		return
+	}
+	c.state.Paused = paused
+	c.state.Status = "running"
+	if paused {
+		c.state.Status = "paused"
+	}
+	s.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) containerRestart(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupContainer(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", args[0])
+		return
+	}
+	if c.state.Running {
+		s.stop(c)
+	}
+	if err := s.start(c); err != nil {
+		s.notify()
+		writeError(w, http.StatusInternalServerError, "%s", err)
+		return
+	}
+	s.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,125 @@
+package fakeengine
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	. "gopkg.in/check.v1"
+)
+
+func (s *ServerTest) start(c *C, image string) string {
+	id := s.create(c, image, nil)
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+	return id
+}
+
+func (s *ServerTest) inspect(c *C, id string) types.ContainerJSON {
+	inspection, err := s.docker.ContainerInspect(context.Background(), id)
+	c.Assert(err, IsNil)
+	return inspection
+}
+
+func (s *ServerTest) TestParseSignal(c *C) {
+	for _, value := range []string{"SIGTERM", "TERM", "term", "15"} {
+		name, number, err := parseSignal(value)
+		c.Assert(err, IsNil)
+		c.Assert(name, Equals, "SIGTERM")
+		c.Assert(number, Equals, 15)
+	}
+	_, _, err := parseSignal("SIGNOPE")
+	c.Assert(err, ErrorMatches, "Invalid signal: SIGNOPE")
+	_, _, err = parseSignal("99")
+	c.Assert(err, ErrorMatches, "Invalid signal: 99")
+}
+
+func (s *ServerTest) TestStop(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.start(c, "test")
+	timeout := time.Second
+	c.Assert(s.docker.ContainerStop(context.Background(), id, &timeout), IsNil)
+	inspection := s.inspect(c, id)
+	c.Assert(inspection.State.Status, Equals, "exited")
+	c.Assert(inspection.State.ExitCode, Equals, 143)
+	signals, err := s.server.Signals(id)
+	c.Assert(err, IsNil)
+	c.Assert(signals, DeepEquals, []string{"SIGTERM"})
+
+	// Stopping a stopped container is not an error.
+	c.Assert(s.docker.ContainerStop(context.Background(), id, &timeout), IsNil)
+	err = s.docker.ContainerStop(context.Background(), "missing", &timeout)
+	c.Assert(err, ErrorMatches, ".*No such container.*")
+}
+
+func (s *ServerTest) TestStopSignalHandled(c *C) {
+	s.server.AddImage("test", &Image{
+		Config:         container.Config{StopSignal: "SIGQUIT"},
+		HandledSignals: []string{"QUIT"},
+	})
+	id := s.start(c, "test")
+	c.Assert(s.docker.ContainerStop(context.Background(), id, nil), IsNil)
+	c.Assert(s.inspect(c, id).State.ExitCode, Equals, 137)
+	signals, err := s.server.Signals(id)
+	c.Assert(err, IsNil)
+	c.Assert(signals, DeepEquals, []string{"SIGQUIT", "SIGKILL"})
+}
+
+func (s *ServerTest) TestKill(c *C) {
+	s.server.AddImage("test", &Image{HandledSignals: []string{"SIGHUP"}})
+	id := s.start(c, "test")
+	c.Assert(s.docker.ContainerKill(context.Background(), id, "HUP"), IsNil)
+	c.Assert(s.inspect(c, id).State.Running, Equals, true)
+	c.Assert(s.docker.ContainerKill(context.Background(), id, "nope"), ErrorMatches, ".*Invalid signal: nope")
+	c.Assert(s.docker.ContainerKill(context.Background(), id, ""), IsNil)
+	inspection := s.inspect(c, id)
+	c.Assert(inspection.State.Running, Equals, false)
+	c.Assert(inspection.State.ExitCode, Equals, 137)
+	c.Assert(s.docker.ContainerKill(context.Background(), id, ""), ErrorMatches, ".*is not running")
+	signals, err := s.server.Signals(id)
+	c.Assert(err, IsNil)
+	c.Assert(signals, DeepEquals, []string{"SIGHUP", "SIGKILL"})
+}
+
+func (s *ServerTest) TestPause(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.start(c, "test")
+	c.Assert(s.docker.ContainerUnpause(context.Background(), id), ErrorMatches, ".*is not paused")
+	c.Assert(s.docker.ContainerPause(context.Background(), id), IsNil)
+	c.Assert(s.docker.ContainerPause(context.Background(), id), ErrorMatches, ".*is already paused")
+	inspection := s.inspect(c, id)
+	c.Assert(inspection.State.Status, Equals, "paused")
+	c.Assert(inspection.State.Paused, Equals, true)
+	_, err := s.docker.ContainerExecCreate(context.Background(), id, types.ExecConfig{Cmd: []string{"true"}})
+	c.Assert(err, ErrorMatches, ".*is paused.*")
+
+	c.Assert(s.docker.ContainerUnpause(context.Background(), id), IsNil)
+	inspection = s.inspect(c, id)
+	c.Assert(inspection.State.Status, Equals, "running")
+	c.Assert(inspection.State.Paused, Equals, false)
+
+	c.Assert(s.docker.ContainerPause(context.Background(), id), IsNil)
+	c.Assert(s.docker.ContainerStop(context.Background(), id, nil), IsNil)
+	c.Assert(s.inspect(c, id).State.Paused, Equals, false)
+	c.Assert(s.docker.ContainerPause(context.Background(), id), ErrorMatches, ".*is not running")
+}
+
+func (s *ServerTest) TestRestart(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.start(c, "test")
+	before := s.inspect(c, id)
+	c.Assert(s.docker.ContainerRestart(context.Background(), id, nil), IsNil)
+	after := s.inspect(c, id)
+	c.Assert(after.State.Running, Equals, true)
+	c.Assert(after.NetworkSettings.Ports["80/tcp"], HasLen, 1)
+	c.Assert(after.NetworkSettings.Ports["80/tcp"][0].HostPort, Not(Equals),
+		before.NetworkSettings.Ports["80/tcp"][0].HostPort)
+	signals, err := s.server.Signals(id)
+	c.Assert(err, IsNil)
+	c.Assert(signals, DeepEquals, []string{"SIGTERM"})
+
+	// Restarting a stopped container starts it.
+	c.Assert(s.docker.ContainerStop(context.Background(), id, nil), IsNil)
+	c.Assert(s.docker.ContainerRestart(context.Background(), id, nil), IsNil)
+	c.Assert(s.inspect(c, id).State.Running, Equals, true)
+}
@@ -0,0 +1,343 @@
+package fakeengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+)
+
+// predefinedNetworks maps the networks which exist on every Server to
+// their drivers.
+var predefinedNetworks = map[string]string{"bridge": "bridge", "host": "host", "none": "null"}
+
+type fakeNetwork struct {
+	id         string
+	name       string
+	driver     string
+	internal   bool
+	labels     map[string]string
+	created    time.Time
+	subnet     int
+	nextIP     int
+	predefined bool
+}
+
+// endpoint is a container's connection to a network.
+type endpoint struct {
+	network  *fakeNetwork
+	settings network.EndpointSettings
+}
+
+// addressable returns true if containers on the network are assigned
+// an IP address.
+func (n *fakeNetwork) addressable() bool {
+	return n.driver == "bridge"
+}
+
+func (n *fakeNetwork) resource(containers []*fakeContainer) types.NetworkResource {
+	resource := types.NetworkResource{
+		Name:       n.name,
+		ID:         n.id,
+		Created:    n.created,
+		Scope:      "local",
+		Driver:     n.driver,
+		Internal:   n.internal,
+		Labels:     n.labels,
+		Options:    map[string]string{},
+		Containers: map[string]types.EndpointResource{},
+	}
+	if n.addressable() {
+		resource.IPAM = network.IPAM{
+			Driver: "default",
+			Config: []network.IPAMConfig{{
+				Subnet:  fmt.Sprintf("172.%d.0.0/16", n.subnet),
+				Gateway: fmt.Sprintf("172.%d.0.1", n.subnet),
+			}},
+		}
+	}
+	for _, c := range containers {
+		if e, ok := c.endpoints[n.id]; ok && e.settings.EndpointID != "" {
+			resource.Containers[c.id] = types.EndpointResource{
+				Name:        c.name,
+				EndpointID:  e.settings.EndpointID,
+				MacAddress:  e.settings.MacAddress,
+				IPv4Address: fmt.Sprintf("%s/%d", e.settings.IPAddress, e.settings.IPPrefixLen),
+			}
+		}
+	}
+	return resource
+}
+
+// addNetwork registers a new network. The caller must hold s.mu.
+func (s *Server) addNetwork(name string, driver string) *fakeNetwork {
+	n := &fakeNetwork{
+		id:      s.nextID(),
+		name:    name,
+		driver:  driver,
+		labels:  map[string]string{},
+		created: time.Now().UTC(),
+		subnet:  17 + len(s.networks),
+		nextIP:  2,
+	}
+	s.networks[n.id] = n
+	return n
+}
+
+// lookupNetwork finds a network by id, name or unique id prefix. The
+// caller must hold s.mu.
+func (s *Server) lookupNetwork(ref string) (*fakeNetwork, bool) {
+	if n, ok := s.networks[ref]; ok {
+		return n, true
+	}
+	var found *fakeNetwork
+	for _, n := range s.networks {
+		if n.name == ref {
+			return n, true
+		}
+		if strings.HasPrefix(n.id, ref) {
+			if found != nil {
+				return nil, false
+			}
+			found = n
+		}
+	}
+	return found, found != nil
+}
+
+// connect adds an endpoint for c on n, allocating an address if c is
+// running. The caller must hold s.mu.
+func (s *Server) connect(c *fakeContainer, n *fakeNetwork, settings *network.EndpointSettings) {
+	e := &endpoint{network: n}
+	if settings != nil {
+		e.settings.Aliases = append([]string{}, settings.Aliases...)
+		e.settings.Links = settings.Links
+		e.settings.IPAMConfig = settings.IPAMConfig
+	}
+	if !n.predefined {
+		e.settings.Aliases = append(e.settings.Aliases, c.id[:12])
+	}
+	e.settings.NetworkID = n.id
+	c.endpoints[n.id] = e
+	if c.state.Running {
+		s.allocate(e)
+	}
+}
+
+// allocate assigns an address to an endpoint. The caller must hold s.mu.
+func (s *Server) allocate(e *endpoint) {
+	e.settings.EndpointID = s.nextID()
+	if !e.network.addressable() {
+		return
+	}
+	n := e.network
+	e.settings.IPAddress = fmt.Sprintf("172.%d.%d.%d", n.subnet, n.nextIP/256, n.nextIP%256)
+	e.settings.Gateway = fmt.Sprintf("172.%d.0.1", n.subnet)
+	e.settings.IPPrefixLen = 16
+	e.settings.MacAddress = fmt.Sprintf("02:42:ac:%02x:%02x:%02x", n.subnet, n.nextIP/256, n.nextIP%256)
+	n.nextIP++
+}
+
+// release removes the addresses assigned to a container's endpoints, as
+// happens when the container stops.
+func release(c *fakeContainer) {
+	for _, e := range c.endpoints {
+		e.settings.EndpointID = ""
+		e.settings.IPAddress = ""
+		e.settings.Gateway = ""
+		e.settings.IPPrefixLen = 0
+		e.settings.MacAddress = ""
+	}
+}
+
+// networkSettings returns the per network settings for a container
+// keyed by network name.
+func (c *fakeContainer) networkSettings() map[string]*network.EndpointSettings {
+	settings := map[string]*network.EndpointSettings{}
+	for _, e := range c.endpoints {
+		copied := e.settings
+		settings[e.network.name] = &copied
+	}
+	return settings
+}
+
+func (s *Server) networkCreate(w http.ResponseWriter, r *http.Request, args []string) {
+	request := types.NetworkCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	if request.Name == "" {
+		writeError(w, http.StatusBadRequest, "network name cannot be empty")
+		return
+	}
+	if request.Driver == "" {
+		request.Driver = "bridge"
+	}
+	if request.Driver != "bridge" {
+		writeError(w, http.StatusNotFound, "plugin %q not found", request.Driver)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.networks {
+		if existing.name == request.Name && (request.CheckDuplicate || existing.predefined) {
+			writeError(w, http.StatusConflict, "network with name %s already exists", request.Name)
+			return
+		}
+	}
+
+	n := s.addNetwork(request.Name, request.Driver)
+	n.internal = request.Internal
+	for key, value := range request.Labels {
+		n.labels[key] = value
+	}
+	writeJSON(w, http.StatusCreated, types.NetworkCreateResponse{ID: n.id})
+}
+
+func (s *Server) networkList(w http.ResponseWriter, r *http.Request, args []string) {
+	filter, err := filters.FromParam(r.URL.Query().Get("filters"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	containers := s.sortedContainers()
+	networks := []types.NetworkResource{}
+	for _, n := range s.networks {
+		if filter.Include("name") && !anyValue(filter.Get("name"), func(v string) bool { return strings.Contains(n.name, v) }) {
+			continue
+		}
+		if filter.Include("id") && !anyValue(filter.Get("id"), func(v string) bool { return strings.HasPrefix(n.id, v) }) {
+			continue
+		}
+		if filter.Include("driver") && !filter.ExactMatch("driver", n.driver) {
+			continue
+		}
+		if filter.Include("label") && !filter.MatchKVList("label", n.labels) {
+			continue
+		}
+		if filter.Include("type") {
+			kind := "custom"
+			if n.predefined {
+				kind = "builtin"
+			}
+			if !filter.ExactMatch("type", kind) {
+				continue
+			}
+		}
+		networks = append(networks, n.resource(containers))
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].Name < networks[j].Name
+	})
+	writeJSON(w, http.StatusOK, networks)
+}
+
+func (s *Server) networkInspect(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.lookupNetwork(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "network %s not found", args[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, n.resource(s.sortedContainers()))
+}
+
+func (s *Server) networkRemove(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.lookupNetwork(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "network %s not found", args[0])
+		return
+	}
+	if n.predefined {
+		writeError(w, http.StatusForbidden, "%s is a pre-defined network and cannot be removed", n.name)
+		return
+	}
+	for _, c := range s.containers {
+		if e, ok := c.endpoints[n.id]; ok && e.settings.EndpointID != "" {
+			writeError(w, http.StatusForbidden,
+				"error while removing network: network %s id %s has active endpoints", n.name, n.id)
+			return
+		}
+	}
+	for _, c := range s.containers {
+		delete(c.endpoints, n.id)
+	}
+	delete(s.networks, n.id)
+	s.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) networkConnect(w http.ResponseWriter, r *http.Request, args []string) {
+	request := types.NetworkConnect{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.lookupNetwork(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "network %s not found", args[0])
+		return
+	}
+	c, ok := s.lookupContainer(request.Container)
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", request.Container)
+		return
+	}
+	if n.driver != "bridge" {
+		writeError(w, http.StatusBadRequest,
+			"container cannot be disconnected from host network or connected to host network")
+		return
+	}
+	if _, ok := c.endpoints[n.id]; ok {
+		writeError(w, http.StatusForbidden, "endpoint with name %s already exists in network %s", c.name, n.name)
+		return
+	}
+	s.connect(c, n, request.EndpointConfig)
+	s.notify()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) networkDisconnect(w http.ResponseWriter, r *http.Request, args []string) {
+	request := types.NetworkDisconnect{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.lookupNetwork(args[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "network %s not found", args[0])
+		return
+	}
+	c, ok := s.lookupContainer(request.Container)
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such container: %s", request.Container)
+		return
+	}
+	if _, ok := c.endpoints[n.id]; !ok {
+		writeError(w, http.StatusForbidden, "container %s is not connected to network %s", c.id, n.name)
+		return
+	}
+	delete(c.endpoints, n.id)
+	s.notify()
+	w.WriteHeader(http.StatusOK)
+}
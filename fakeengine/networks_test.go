@@ -0,0 +1,134 @@
+package fakeengine
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	. "gopkg.in/check.v1"
+)
+
+func (s *ServerTest) createNetwork(c *C, name string) string {
+	created, err := s.docker.NetworkCreate(context.Background(), name, types.NetworkCreate{
+		CheckDuplicate: true, Labels: map[string]string{"dockertest": "1"},
+	})
+	c.Assert(err, IsNil)
+	return created.ID
+}
+
+func (s *ServerTest) TestNetworkPredefined(c *C) {
+	networks, err := s.docker.NetworkList(context.Background(), types.NetworkListOptions{})
+	c.Assert(err, IsNil)
+	names := []string{}
+	for _, n := range networks {
+		names = append(names, n.Name)
+	}
+	c.Assert(names, DeepEquals, []string{"bridge", "host", "none"})
+
+	err = s.docker.NetworkRemove(context.Background(), "bridge")
+	c.Assert(err, ErrorMatches, ".*pre-defined network.*")
+	_, err = s.docker.NetworkCreate(context.Background(), "host", types.NetworkCreate{})
+	c.Assert(err, ErrorMatches, ".*already exists.*")
+}
+
+func (s *ServerTest) TestNetworkCreate(c *C) {
+	id := s.createNetwork(c, "testing")
+	_, err := s.docker.NetworkCreate(context.Background(), "testing", types.NetworkCreate{CheckDuplicate: true})
+	c.Assert(err, ErrorMatches, ".*already exists.*")
+	_, err = s.docker.NetworkCreate(context.Background(), "other", types.NetworkCreate{Driver: "overlay"})
+	c.Assert(err, ErrorMatches, ".*plugin \"overlay\" not found.*")
+
+	resource, err := s.docker.NetworkInspect(context.Background(), "testing", types.NetworkInspectOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(resource.ID, Equals, id)
+	c.Assert(resource.Driver, Equals, "bridge")
+	c.Assert(resource.Labels, DeepEquals, map[string]string{"dockertest": "1"})
+	c.Assert(resource.IPAM.Config, HasLen, 1)
+
+	args := filters.NewArgs()
+	args.Add("label", "dockertest=1")
+	networks, err := s.docker.NetworkList(context.Background(), types.NetworkListOptions{Filters: args})
+	c.Assert(err, IsNil)
+	c.Assert(networks, HasLen, 1)
+	c.Assert(networks[0].ID, Equals, id)
+
+	c.Assert(s.docker.NetworkRemove(context.Background(), id), IsNil)
+	_, err = s.docker.NetworkInspect(context.Background(), id, types.NetworkInspectOptions{})
+	c.Assert(err, ErrorMatches, ".*No such network.*")
+	err = s.docker.NetworkRemove(context.Background(), id)
+	c.Assert(err, ErrorMatches, ".*No such network.*")
+}
+
+func (s *ServerTest) TestNetworkContainerAddresses(c *C) {
+	s.server.AddImage("test", &Image{})
+	s.createNetwork(c, "first")
+	s.createNetwork(c, "second")
+	created, err := s.docker.ContainerCreate(
+		context.Background(),
+		&container.Config{Image: "test"},
+		&container.HostConfig{NetworkMode: "first"},
+		&network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+			"first": {Aliases: []string{"db"}},
+		}}, "")
+	c.Assert(err, IsNil)
+	err = s.docker.NetworkConnect(context.Background(), "second", created.ID, &network.EndpointSettings{Aliases: []string{"cache"}})
+	c.Assert(err, IsNil)
+	err = s.docker.NetworkConnect(context.Background(), "second", created.ID, nil)
+	c.Assert(err, ErrorMatches, ".*already exists.*")
+
+	inspection, err := s.docker.ContainerInspect(context.Background(), created.ID)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.NetworkSettings.Networks, HasLen, 2)
+	c.Assert(inspection.NetworkSettings.Networks["first"].IPAddress, Equals, "")
+	c.Assert(inspection.NetworkSettings.Networks["first"].Aliases, DeepEquals, []string{"db", created.ID[:12]})
+
+	c.Assert(s.docker.ContainerStart(context.Background(), created.ID, types.ContainerStartOptions{}), IsNil)
+	inspection, err = s.docker.ContainerInspect(context.Background(), created.ID)
+	c.Assert(err, IsNil)
+	first := inspection.NetworkSettings.Networks["first"]
+	second := inspection.NetworkSettings.Networks["second"]
+	c.Assert(first.IPAddress, Not(Equals), "")
+	c.Assert(second.IPAddress, Not(Equals), "")
+	c.Assert(first.IPAddress, Not(Equals), second.IPAddress)
+	c.Assert(second.Aliases, DeepEquals, []string{"cache", created.ID[:12]})
+
+	resource, err := s.docker.NetworkInspect(context.Background(), "first", types.NetworkInspectOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(resource.Containers, HasLen, 1)
+	c.Assert(resource.Containers[created.ID].IPv4Address, Equals, first.IPAddress+"/16")
+
+	err = s.docker.NetworkRemove(context.Background(), "first")
+	c.Assert(err, ErrorMatches, ".*active endpoints.*")
+	c.Assert(s.docker.NetworkDisconnect(context.Background(), "first", created.ID, true), IsNil)
+	c.Assert(s.docker.NetworkRemove(context.Background(), "first"), IsNil)
+
+	s.server.Exit(created.ID, 0)
+	inspection, err = s.docker.ContainerInspect(context.Background(), created.ID)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.NetworkSettings.Networks["second"].IPAddress, Equals, "")
+}
+
+func (s *ServerTest) TestNetworkCreateContainerErrors(c *C) {
+	s.server.AddImage("test", &Image{})
+	_, err := s.docker.ContainerCreate(
+		context.Background(), &container.Config{Image: "test"},
+		&container.HostConfig{NetworkMode: "missing"}, &network.NetworkingConfig{}, "")
+	c.Assert(err, ErrorMatches, ".*network missing not found.*")
+
+	_, err = s.docker.ContainerCreate(
+		context.Background(), &container.Config{Image: "test"},
+		&container.HostConfig{},
+		&network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+			"bridge": {Aliases: []string{"db"}},
+		}}, "")
+	c.Assert(err, ErrorMatches, ".*alias.*")
+
+	id := s.create(c, "test", nil)
+	inspection, err := s.docker.ContainerInspect(context.Background(), id)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.HostConfig.NetworkMode, Equals, container.NetworkMode("default"))
+	c.Assert(inspection.NetworkSettings.Networks, HasLen, 1)
+	c.Assert(inspection.NetworkSettings.Networks["bridge"], NotNil)
+}
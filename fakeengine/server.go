@@ -0,0 +1,219 @@
+// Package fakeengine provides an in-process implementation of the subset
+// of the Docker Engine HTTP API used by dockertest. Containers never run
+// any processes; their state is kept in memory and may be manipulated by
+// tests. This allows code built on dockertest to be tested on machines
+// which do not have access to Docker.
+package fakeengine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// APIVersion is the Docker Engine API version reported by the Server.
+const APIVersion = "1.32"
+
+var versionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// Server is a fake Docker Engine. The zero value is not usable, use
+// NewServer or NewUnixServer instead.
+type Server struct {
+	mu         sync.Mutex
+	changed    chan struct{}
+	closed     chan struct{}
+	closeOnce  sync.Once
+	listener   net.Listener
+	server     *http.Server
+	host       string
+	counter    int
+	nextPort   uint16
+	images     map[string]*Image
+	registry   map[string]*Image
+	auths      []types.AuthConfig
+	containers map[string]*fakeContainer
+	execs      map[string]*fakeExec
+	networks   map[string]*fakeNetwork
+	volumes    map[string]*fakeVolume
+	routes     []route
+}
+
+// NewServer starts a new *Server listening on a random port on the
+// loopback interface.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return serve(listener, "tcp://"+listener.Addr().String()), nil
+}
+
+// NewUnixServer starts a new *Server listening on the provided unix socket
+// path. Any existing file at path will be removed first.
+func NewUnixServer(path string) (*Server, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return serve(listener, "unix://"+path), nil
+}
+
+func serve(listener net.Listener, host string) *Server {
+	s := &Server{
+		changed:    make(chan struct{}),
+		closed:     make(chan struct{}),
+		listener:   listener,
+		host:       host,
+		nextPort:   32768,
+		images:     map[string]*Image{},
+		registry:   map[string]*Image{},
+		containers: map[string]*fakeContainer{},
+		execs:      map[string]*fakeExec{},
+		networks:   map[string]*fakeNetwork{},
+		volumes:    map[string]*fakeVolume{},
+	}
+	for _, name := range []string{"bridge", "host", "none"} {
+		s.addNetwork(name, predefinedNetworks[name]).predefined = true
+	}
+	s.routes = s.routeTable()
+	s.server = &http.Server{Handler: http.HandlerFunc(s.route)}
+	go s.server.Serve(listener) // nolint: errcheck
+	return s
+}
+
+// Host returns the address of the server in the form expected by
+// DOCKER_HOST, for example tcp://127.0.0.1:12345.
+func (s *Server) Host() string {
+	return s.host
+}
+
+// Client returns a new docker client which is configured to talk to
+// this server.
+func (s *Server) Client() (*client.Client, error) {
+	return client.NewClient(s.host, APIVersion, nil, nil)
+}
+
+// Close stops the server. Any streaming requests, such as followed logs,
+// are terminated.
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	s.mu.Lock()
+	s.notify()
+	s.mu.Unlock()
+	return s.server.Close()
+}
+
+// notify wakes up any request waiting for a state change. The caller
+// must hold s.mu.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// nextID returns a new random, 64 character hex id. The caller must
+// hold s.mu.
+func (s *Server) nextID() string {
+	s.counter++
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(data)
+}
+
+type handler func(w http.ResponseWriter, r *http.Request, args []string)
+
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+	handler handler
+}
+
+func (s *Server) routeTable() []route {
+	return []route{
+		{"GET", regexp.MustCompile(`^/_ping$`), s.ping},
+		{"POST", regexp.MustCompile(`^/images/create$`), s.imageCreate},
+		{"POST", regexp.MustCompile(`^/build$`), s.imageBuild},
+		{"POST", regexp.MustCompile(`^/containers/create$`), s.containerCreate},
+		{"GET", regexp.MustCompile(`^/containers/json$`), s.containerList},
+		{"GET", regexp.MustCompile(`^/containers/([^/]+)/json$`), s.containerInspect},
+		{"POST", regexp.MustCompile(`^/containers/([^/]+)/start$`), s.containerStart},
+		{"POST", regexp.MustCompile(`^/containers/([^/]+)/stop$`), s.containerStop},
+		{"POST", regexp.MustCompile(`^/containers/([^/]+)/kill$`), s.containerKill},
+		{"POST", regexp.MustCompile(`^/containers/([^/]+)/pause$`), s.containerPause},
+		{"POST", regexp.MustCompile(`^/containers/([^/]+)/unpause$`), s.containerUnpause},
+		{"POST", regexp.MustCompile(`^/containers/([^/]+)/restart$`), s.containerRestart},
+		{"GET", regexp.MustCompile(`^/containers/([^/]+)/logs$`), s.containerLogs},
+		{"POST", regexp.MustCompile(`^/containers/([^/]+)/wait$`), s.containerWait},
+		{"POST", regexp.MustCompile(`^/containers/([^/]+)/exec$`), s.execCreate},
+		{"HEAD", regexp.MustCompile(`^/containers/([^/]+)/archive$`), s.archiveStat},
+		{"GET", regexp.MustCompile(`^/containers/([^/]+)/archive$`), s.archiveGet},
+		{"PUT", regexp.MustCompile(`^/containers/([^/]+)/archive$`), s.archivePut},
+		{"POST", regexp.MustCompile(`^/exec/([^/]+)/start$`), s.execStart},
+		{"GET", regexp.MustCompile(`^/exec/([^/]+)/json$`), s.execInspect},
+		{"DELETE", regexp.MustCompile(`^/containers/([^/]+)$`), s.containerRemove},
+		{"POST", regexp.MustCompile(`^/networks/create$`), s.networkCreate},
+		{"GET", regexp.MustCompile(`^/networks$`), s.networkList},
+		{"GET", regexp.MustCompile(`^/networks/([^/]+)$`), s.networkInspect},
+		{"DELETE", regexp.MustCompile(`^/networks/([^/]+)$`), s.networkRemove},
+		{"POST", regexp.MustCompile(`^/networks/([^/]+)/connect$`), s.networkConnect},
+		{"POST", regexp.MustCompile(`^/networks/([^/]+)/disconnect$`), s.networkDisconnect},
+		{"POST", regexp.MustCompile(`^/volumes/create$`), s.volumeCreate},
+		{"GET", regexp.MustCompile(`^/volumes$`), s.volumeList},
+		{"GET", regexp.MustCompile(`^/volumes/([^/]+)$`), s.volumeInspect},
+		{"DELETE", regexp.MustCompile(`^/volumes/([^/]+)$`), s.volumeRemove},
+	}
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	path := versionPrefix.ReplaceAllString(r.URL.Path, "")
+	for _, entry := range s.routes {
+		if entry.method != r.Method {
+			continue
+		}
+		if match := entry.pattern.FindStringSubmatch(path); match != nil {
+			entry.handler(w, r, match[1:])
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "page not found")
+}
+
+func (s *Server) ping(w http.ResponseWriter, r *http.Request, args []string) {
+	w.Header().Set("API-Version", APIVersion)
+	w.Header().Set("OSType", "linux")
+	fmt.Fprint(w, "OK") // nolint: errcheck
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value) // nolint: errcheck
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, types.ErrorResponse{
+		Message: strings.TrimSpace(fmt.Sprintf(format, args...)),
+	})
+}
+
+// flush sends any buffered data to the client, used by streaming
+// endpoints.
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
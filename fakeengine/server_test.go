@@ -0,0 +1,383 @@
+package fakeengine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type ServerTest struct {
+	server *Server
+	docker *client.Client
+}
+
+var _ = Suite(&ServerTest{})
+
+func (s *ServerTest) SetUpTest(c *C) {
+	server, err := NewServer()
+	c.Assert(err, IsNil)
+	docker, err := server.Client()
+	c.Assert(err, IsNil)
+	s.server = server
+	s.docker = docker
+}
+
+func (s *ServerTest) TearDownTest(c *C) {
+	c.Assert(s.docker.Close(), IsNil)
+	c.Assert(s.server.Close(), IsNil)
+}
+
+func (s *ServerTest) create(c *C, image string, labels map[string]string) string {
+	created, err := s.docker.ContainerCreate(
+		context.Background(),
+		&container.Config{Image: image, Labels: labels},
+		&container.HostConfig{PortBindings: nat.PortMap{"80/tcp": {{}}}},
+		&network.NetworkingConfig{}, "")
+	c.Assert(err, IsNil)
+	return created.ID
+}
+
+func (s *ServerTest) TestPing(c *C) {
+	ping, err := s.docker.Ping(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(ping.APIVersion, Equals, APIVersion)
+}
+
+func (s *ServerTest) TestUnixServer(c *C) {
+	dir, err := ioutil.TempDir("", "fakeengine")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	server, err := NewUnixServer(filepath.Join(dir, "docker.sock"))
+	c.Assert(err, IsNil)
+	defer server.Close() // nolint: errcheck
+	c.Assert(server.Host(), Equals, "unix://"+filepath.Join(dir, "docker.sock"))
+
+	docker, err := server.Client()
+	c.Assert(err, IsNil)
+	_, err = docker.Ping(context.Background())
+	c.Assert(err, IsNil)
+}
+
+func (s *ServerTest) TestPull(c *C) {
+	s.server.AddRemoteImage("nginx", &Image{})
+	c.Assert(s.server.HasImage("nginx:latest"), Equals, false)
+
+	reader, err := s.docker.ImagePull(context.Background(), "nginx", types.ImagePullOptions{})
+	c.Assert(err, IsNil)
+	body, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Matches, `(?s).*Downloaded newer image for nginx:latest.*`)
+	c.Assert(s.server.HasImage("nginx:latest"), Equals, true)
+}
+
+func (s *ServerTest) TestPullMissing(c *C) {
+	_, err := s.docker.ImagePull(context.Background(), "missing", types.ImagePullOptions{})
+	c.Assert(err, ErrorMatches, ".*repository does not exist.*")
+}
+
+func (s *ServerTest) TestPullError(c *C) {
+	s.server.AddRemoteImage("broken", &Image{PullError: "manifest unknown"})
+	reader, err := s.docker.ImagePull(context.Background(), "broken", types.ImagePullOptions{})
+	c.Assert(err, IsNil)
+	body, err := ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Matches, `(?s).*"error":"manifest unknown".*`)
+	c.Assert(s.server.HasImage("broken"), Equals, false)
+}
+
+func (s *ServerTest) TestCreateMissingImage(c *C) {
+	_, err := s.docker.ContainerCreate(
+		context.Background(), &container.Config{Image: "missing"}, nil, nil, "")
+	c.Assert(client.IsErrNotFound(err), Equals, true)
+}
+
+func (s *ServerTest) TestCreateNameConflict(c *C) {
+	s.server.AddImage("test", &Image{})
+	config := &container.Config{Image: "test"}
+	_, err := s.docker.ContainerCreate(context.Background(), config, nil, nil, "name")
+	c.Assert(err, IsNil)
+	_, err = s.docker.ContainerCreate(context.Background(), config, nil, nil, "name")
+	c.Assert(err, ErrorMatches, `.*Conflict. The container name "/name" is already in use.*`)
+}
+
+func (s *ServerTest) TestCreateMergesImageConfig(c *C) {
+	s.server.AddImage("test", &Image{Config: container.Config{
+		Entrypoint: []string{"/entrypoint"},
+		Cmd:        []string{"serve"},
+		User:       "app",
+	}})
+	for _, test := range []struct {
+		requested  container.Config
+		entrypoint strslice.StrSlice
+		cmd        strslice.StrSlice
+	}{
+		{container.Config{}, strslice.StrSlice{"/entrypoint"}, strslice.StrSlice{"serve"}},
+		{container.Config{Cmd: []string{"migrate"}}, strslice.StrSlice{"/entrypoint"}, strslice.StrSlice{"migrate"}},
+		{container.Config{Entrypoint: []string{"/bin/sh"}}, strslice.StrSlice{"/bin/sh"}, nil},
+		{container.Config{Entrypoint: []string{""}, Cmd: []string{"ls"}}, nil, strslice.StrSlice{"ls"}},
+	} {
+		test.requested.Image = "test"
+		created, err := s.docker.ContainerCreate(context.Background(), &test.requested, nil, nil, "")
+		c.Assert(err, IsNil)
+		inspection, err := s.docker.ContainerInspect(context.Background(), created.ID)
+		c.Assert(err, IsNil)
+		c.Assert(inspection.Config.Entrypoint, DeepEquals, test.entrypoint)
+		c.Assert(inspection.Config.Cmd, DeepEquals, test.cmd)
+		c.Assert(inspection.Config.User, Equals, "app")
+		c.Assert(inspection.Config.Hostname, Equals, created.ID[:12])
+	}
+}
+
+func (s *ServerTest) TestCreateResources(c *C) {
+	s.server.AddImage("test", &Image{})
+	for _, test := range []struct {
+		resources container.Resources
+		err       string
+	}{
+		{container.Resources{Memory: 1024}, ".*Minimum memory limit allowed is 4MB.*"},
+		{container.Resources{MemorySwap: 64 << 20}, ".*always set the Memory limit.*"},
+		{container.Resources{Memory: 64 << 20, MemorySwap: 32 << 20}, ".*memoryswap limit should be larger.*"},
+	} {
+		_, err := s.docker.ContainerCreate(context.Background(), &container.Config{Image: "test"},
+			&container.HostConfig{Resources: test.resources}, nil, "")
+		c.Assert(err, ErrorMatches, test.err)
+	}
+}
+
+func (s *ServerTest) TestStartAndInspect(c *C) {
+	s.server.AddImage("test", &Image{Config: container.Config{Env: []string{"A=1"}}})
+	id := s.create(c, "test", map[string]string{"foo": "bar"})
+
+	inspection, err := s.docker.ContainerInspect(context.Background(), id)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.State.Status, Equals, "created")
+	c.Assert(inspection.Config.Env, DeepEquals, []string{"A=1"})
+
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+	inspection, err = s.docker.ContainerInspect(context.Background(), id)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.State.Running, Equals, true)
+	c.Assert(inspection.NetworkSettings.Ports["80/tcp"], HasLen, 1)
+}
+
+func (s *ServerTest) TestStartError(c *C) {
+	s.server.AddImage("test", &Image{StartError: "oci runtime error"})
+	id := s.create(c, "test", nil)
+	err := s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{})
+	c.Assert(err, ErrorMatches, ".*oci runtime error")
+}
+
+func (s *ServerTest) TestExits(c *C) {
+	s.server.AddImage("test", &Image{Exits: true, ExitCode: 3})
+	id := s.create(c, "test", nil)
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+	inspection, err := s.docker.ContainerInspect(context.Background(), id)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.State.Status, Equals, "exited")
+	c.Assert(inspection.State.ExitCode, Equals, 3)
+}
+
+func (s *ServerTest) TestListFilters(c *C) {
+	s.server.AddImage("test", &Image{})
+	s.server.AddImage("other", &Image{})
+	first := s.create(c, "test", map[string]string{"a": "1"})
+	second := s.create(c, "other", map[string]string{"a": "2"})
+	c.Assert(s.docker.ContainerStart(context.Background(), second, types.ContainerStartOptions{}), IsNil)
+
+	list := func(all bool, args ...filters.KeyValuePair) []string {
+		containers, err := s.docker.ContainerList(context.Background(), types.ContainerListOptions{
+			All: all, Filters: filters.NewArgs(args...),
+		})
+		c.Assert(err, IsNil)
+		ids := []string{}
+		for _, entry := range containers {
+			ids = append(ids, entry.ID)
+		}
+		return ids
+	}
+
+	c.Assert(list(false), DeepEquals, []string{second})
+	c.Assert(list(true), DeepEquals, []string{second, first})
+	c.Assert(list(true, filters.Arg("label", "a=1")), DeepEquals, []string{first})
+	c.Assert(list(true, filters.Arg("label", "a")), DeepEquals, []string{second, first})
+	c.Assert(list(true, filters.Arg("ancestor", "other")), DeepEquals, []string{second})
+	c.Assert(list(true, filters.Arg("id", first[:12])), DeepEquals, []string{first})
+	c.Assert(list(true, filters.Arg("status", "created")), DeepEquals, []string{first})
+	c.Assert(list(true, filters.Arg("since", first)), DeepEquals, []string{second})
+	c.Assert(list(true, filters.Arg("before", second)), DeepEquals, []string{first})
+	c.Assert(list(true, filters.Arg("name", "missing")), DeepEquals, []string{})
+	c.Assert(list(true, filters.Arg("network", "bridge")), DeepEquals, []string{second, first})
+	c.Assert(list(true, filters.Arg("network", "host")), DeepEquals, []string{})
+	c.Assert(list(true, filters.Arg("health", "none")), DeepEquals, []string{second, first})
+	c.Assert(list(true, filters.Arg("health", "healthy")), DeepEquals, []string{})
+
+	c.Assert(s.server.Exit(second, 3), IsNil)
+	c.Assert(list(true, filters.Arg("exited", "3")), DeepEquals, []string{second})
+	c.Assert(list(true, filters.Arg("exited", "0")), DeepEquals, []string{})
+}
+
+func (s *ServerTest) TestSetCreated(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.create(c, "test", nil)
+	created := time.Now().Add(-time.Hour)
+	c.Assert(s.server.SetCreated(id, created), IsNil)
+	containers, err := s.docker.ContainerList(context.Background(), types.ContainerListOptions{All: true})
+	c.Assert(err, IsNil)
+	c.Assert(containers[0].Created, Equals, created.Unix())
+	c.Assert(s.server.SetCreated("missing", created), Equals, ErrNoSuchContainer)
+}
+
+func (s *ServerTest) TestRemove(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.create(c, "test", nil)
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+
+	err := s.docker.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{})
+	c.Assert(err, ErrorMatches, ".*You cannot remove a running container.*")
+	c.Assert(s.docker.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: true}), IsNil)
+
+	err = s.docker.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: true})
+	c.Assert(err, ErrorMatches, ".*No such container.*")
+}
+
+func (s *ServerTest) TestLogs(c *C) {
+	s.server.AddImage("test", &Image{Stdout: []string{"one", "two"}, Stderr: []string{"three"}})
+	id := s.create(c, "test", nil)
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+
+	reader, err := s.docker.ContainerLogs(context.Background(), id, types.ContainerLogsOptions{
+		ShowStdout: true, ShowStderr: true,
+	})
+	c.Assert(err, IsNil)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	_, err = stdcopy.StdCopy(stdout, stderr, reader)
+	c.Assert(err, IsNil)
+	c.Assert(stdout.String(), Equals, "one\ntwo\n")
+	c.Assert(stderr.String(), Equals, "three\n")
+
+	reader, err = s.docker.ContainerLogs(context.Background(), id, types.ContainerLogsOptions{
+		ShowStdout: true, Tail: "1",
+	})
+	c.Assert(err, IsNil)
+	stdout.Reset()
+	_, err = stdcopy.StdCopy(stdout, ioutil.Discard, reader)
+	c.Assert(err, IsNil)
+	c.Assert(stdout.String(), Equals, "two\n")
+}
+
+func (s *ServerTest) TestLogsFollow(c *C) {
+	s.server.AddImage("test", &Image{Stdout: []string{"one"}})
+	id := s.create(c, "test", nil)
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+
+	reader, err := s.docker.ContainerLogs(context.Background(), id, types.ContainerLogsOptions{
+		ShowStdout: true, Follow: true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.server.WriteStdout(id, "two"), IsNil)
+	c.Assert(s.server.Exit(id, 0), IsNil)
+
+	stdout := &bytes.Buffer{}
+	_, err = stdcopy.StdCopy(stdout, ioutil.Discard, reader)
+	c.Assert(err, IsNil)
+	c.Assert(stdout.String(), Equals, "one\ntwo\n")
+}
+
+func (s *ServerTest) TestExitNoSuchContainer(c *C) {
+	c.Assert(s.server.Exit("missing", 1), Equals, ErrNoSuchContainer)
+}
+
+func (s *ServerTest) TestPullRequiresAuth(c *C) {
+	s.server.AddRemoteImage("private", &Image{Username: "user", Password: "pass"})
+
+	_, err := s.docker.ImagePull(context.Background(), "private", types.ImagePullOptions{})
+	c.Assert(err, ErrorMatches, ".*authentication required")
+
+	wrong := base64.URLEncoding.EncodeToString([]byte(`{"username":"user","password":"wrong"}`))
+	_, err = s.docker.ImagePull(context.Background(), "private", types.ImagePullOptions{RegistryAuth: wrong})
+	c.Assert(err, ErrorMatches, ".*incorrect username or password")
+
+	auth := base64.URLEncoding.EncodeToString([]byte(`{"username":"user","password":"pass"}`))
+	reader, err := s.docker.ImagePull(context.Background(), "private", types.ImagePullOptions{RegistryAuth: auth})
+	c.Assert(err, IsNil)
+	_, err = ioutil.ReadAll(reader)
+	c.Assert(err, IsNil)
+	c.Assert(s.server.HasImage("private"), Equals, true)
+	c.Assert(s.server.RegistryAuths(), HasLen, 3)
+	c.Assert(s.server.RegistryAuths()[2].Username, Equals, "user")
+}
+
+func (s *ServerTest) TestWait(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.create(c, "test", nil)
+
+	// A created container is not running so this returns immediately.
+	results, errs := s.docker.ContainerWait(context.Background(), id, container.WaitConditionNotRunning)
+	select {
+	case result := <-results:
+		c.Assert(result.StatusCode, Equals, int64(0))
+	case err := <-errs:
+		c.Fatal(err)
+	}
+
+	results, errs = s.docker.ContainerWait(context.Background(), id, container.WaitConditionNextExit)
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+	c.Assert(s.server.Exit(id, 4), IsNil)
+	select {
+	case result := <-results:
+		c.Assert(result.StatusCode, Equals, int64(4))
+	case err := <-errs:
+		c.Fatal(err)
+	}
+
+	results, errs = s.docker.ContainerWait(context.Background(), id, container.WaitConditionRemoved)
+	c.Assert(s.docker.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{}), IsNil)
+	select {
+	case result := <-results:
+		c.Assert(result.StatusCode, Equals, int64(4))
+	case err := <-errs:
+		c.Fatal(err)
+	}
+}
+
+func (s *ServerTest) TestOOMKill(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.create(c, "test", nil)
+	c.Assert(s.server.OOMKill(id), ErrorMatches, ".*is not running")
+	c.Assert(s.docker.ContainerStart(context.Background(), id, types.ContainerStartOptions{}), IsNil)
+	c.Assert(s.server.OOMKill(id), IsNil)
+	inspection, err := s.docker.ContainerInspect(context.Background(), id)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.State.OOMKilled, Equals, true)
+	c.Assert(inspection.State.ExitCode, Equals, 137)
+}
+
+func (s *ServerTest) TestSetHealth(c *C) {
+	s.server.AddImage("test", &Image{})
+	id := s.create(c, "test", nil)
+	c.Assert(s.server.SetHealth("missing", "healthy"), Equals, ErrNoSuchContainer)
+	c.Assert(s.server.SetHealth(id, "healthy"), IsNil)
+	inspection, err := s.docker.ContainerInspect(context.Background(), id)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.State.Health.Status, Equals, "healthy")
+}
@@ -0,0 +1,252 @@
+package fakeengine
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/pkg/errors"
+)
+
+type fakeVolume struct {
+	name      string
+	driver    string
+	labels    map[string]string
+	options   map[string]string
+	created   time.Time
+	anonymous bool
+}
+
+func (v *fakeVolume) resource() *types.Volume {
+	return &types.Volume{
+		CreatedAt:  v.created.Format(time.RFC3339),
+		Driver:     v.driver,
+		Labels:     v.labels,
+		Mountpoint: "/var/lib/docker/volumes/" + v.name + "/_data",
+		Name:       v.name,
+		Options:    v.options,
+		Scope:      "local",
+	}
+}
+
+// addVolume registers a new volume, or returns the existing volume with
+// the same name. The caller must hold s.mu.
+func (s *Server) addVolume(name string, labels map[string]string) *fakeVolume {
+	anonymous := name == ""
+	if anonymous {
+		name = s.nextID()
+	}
+	if v, ok := s.volumes[name]; ok {
+		return v
+	}
+	v := &fakeVolume{
+		anonymous: anonymous,
+		name:      name,
+		driver:    "local",
+		labels:    map[string]string{},
+		options:   map[string]string{},
+		created:   time.Now().UTC(),
+	}
+	for key, value := range labels {
+		v.labels[key] = value
+	}
+	s.volumes[name] = v
+	return v
+}
+
+// users returns the ids of the containers which mount v. The caller must
+// hold s.mu.
+func (s *Server) users(v *fakeVolume) []string {
+	ids := []string{}
+	for _, c := range s.sortedContainers() {
+		for _, point := range c.mounts {
+			if point.Type == mount.TypeVolume && point.Name == v.name {
+				ids = append(ids, c.id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// mountPoints validates the mounts requested when creating a container
+// and returns the resulting mount points, creating any volumes which do
+// not exist yet. The caller must hold s.mu.
+func (s *Server) mountPoints(mounts []mount.Mount) ([]types.MountPoint, error) {
+	points := []types.MountPoint{}
+	targets := map[string]bool{}
+	for _, m := range mounts {
+		if m.Target == "" {
+			return nil, invalidMount(m.Type, "Target must not be empty")
+		}
+		if !path.IsAbs(m.Target) {
+			return nil, invalidMount(m.Type, "invalid mount path: '"+m.Target+"' mount path must be absolute")
+		}
+		target := path.Clean(m.Target)
+		if targets[target] {
+			return nil, errors.Errorf("Duplicate mount point: %s", target)
+		}
+		targets[target] = true
+
+		point := types.MountPoint{
+			Type:        m.Type,
+			Source:      m.Source,
+			Destination: target,
+			RW:          !m.ReadOnly,
+		}
+		switch m.Type {
+		case mount.TypeBind:
+			if m.VolumeOptions != nil || m.TmpfsOptions != nil {
+				return nil, invalidMount(m.Type, "only BindOptions may be specified")
+			}
+			if m.Source == "" {
+				return nil, invalidMount(m.Type, "field Source must not be empty")
+			}
+			if !path.IsAbs(m.Source) {
+				return nil, invalidMount(m.Type, "invalid mount path: '"+m.Source+"' mount path must be absolute")
+			}
+			if _, err := os.Stat(m.Source); err != nil {
+				return nil, invalidMount(m.Type, "bind source path does not exist: "+m.Source)
+			}
+			point.Propagation = mount.PropagationRPrivate
+		case mount.TypeVolume:
+			if m.BindOptions != nil || m.TmpfsOptions != nil {
+				return nil, invalidMount(m.Type, "only VolumeOptions may be specified")
+			}
+			point.Name = m.Source
+			point.Driver = "local"
+			point.Mode = "z"
+		case mount.TypeTmpfs:
+			if m.BindOptions != nil || m.VolumeOptions != nil {
+				return nil, invalidMount(m.Type, "only TmpfsOptions may be specified")
+			}
+			if m.Source != "" {
+				return nil, invalidMount(m.Type, "field Source must not be specified")
+			}
+			if m.TmpfsOptions != nil && m.TmpfsOptions.SizeBytes < 0 {
+				return nil, invalidMount(m.Type, "invalid tmpfs size")
+			}
+		default:
+			return nil, errors.Errorf("invalid mount config: mount type unknown: %q", m.Type)
+		}
+		points = append(points, point)
+	}
+
+	// Volumes are only created once every mount is known to be valid.
+	for i, m := range mounts {
+		if m.Type != mount.TypeVolume {
+			continue
+		}
+		var labels map[string]string
+		if m.VolumeOptions != nil {
+			labels = m.VolumeOptions.Labels
+		}
+		v := s.addVolume(m.Source, labels)
+		points[i].Name = v.name
+		points[i].Source = v.resource().Mountpoint
+	}
+	return points, nil
+}
+
+func invalidMount(kind mount.Type, message string) error {
+	return errors.Errorf("invalid mount config for type %q: %s", kind, message)
+}
+
+func (s *Server) volumeCreate(w http.ResponseWriter, r *http.Request, args []string) {
+	request := volume.VolumesCreateBody{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+	if request.Driver != "" && request.Driver != "local" {
+		writeError(w, http.StatusNotFound, "plugin %q not found", request.Driver)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.addVolume(request.Name, request.Labels)
+	v.anonymous = false
+	for key, value := range request.DriverOpts {
+		v.options[key] = value
+	}
+	writeJSON(w, http.StatusCreated, v.resource())
+}
+
+func (s *Server) volumeList(w http.ResponseWriter, r *http.Request, args []string) {
+	filter, err := filters.FromParam(r.URL.Query().Get("filters"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%s", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	volumes := []*types.Volume{}
+	for _, v := range s.volumes {
+		if filter.Include("name") && !anyValue(filter.Get("name"), func(value string) bool { return strings.Contains(v.name, value) }) {
+			continue
+		}
+		if filter.Include("driver") && !filter.ExactMatch("driver", v.driver) {
+			continue
+		}
+		if filter.Include("label") && !filter.MatchKVList("label", v.labels) {
+			continue
+		}
+		if filter.Include("dangling") {
+			dangling := len(s.users(v)) == 0
+			if !filter.ExactMatch("dangling", "true") && !filter.ExactMatch("dangling", "1") {
+				dangling = !dangling
+			}
+			if !dangling {
+				continue
+			}
+		}
+		volumes = append(volumes, v.resource())
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].Name < volumes[j].Name
+	})
+	writeJSON(w, http.StatusOK, volume.VolumesListOKBody{Volumes: volumes, Warnings: []string{}})
+}
+
+func (s *Server) volumeInspect(w http.ResponseWriter, r *http.Request, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.volumes[args[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "get %s: no such volume", args[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, v.resource())
+}
+
+func (s *Server) volumeRemove(w http.ResponseWriter, r *http.Request, args []string) {
+	force := r.URL.Query().Get("force") == "1"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.volumes[args[0]]
+	if !ok {
+		if force {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeError(w, http.StatusNotFound, "get %s: no such volume", args[0])
+		return
+	}
+	if users := s.users(v); len(users) > 0 {
+		writeError(w, http.StatusConflict, "remove %s: volume is in use - [%s]", v.name, strings.Join(users, ", "))
+		return
+	}
+	delete(s.volumes, v.name)
+	w.WriteHeader(http.StatusNoContent)
+}
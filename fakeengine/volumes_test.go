@@ -0,0 +1,125 @@
+package fakeengine
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	. "gopkg.in/check.v1"
+)
+
+func (s *ServerTest) createWithMounts(c *C, mounts ...mount.Mount) (string, error) {
+	created, err := s.docker.ContainerCreate(
+		context.Background(), &container.Config{Image: "test"},
+		&container.HostConfig{Mounts: mounts}, &network.NetworkingConfig{}, "")
+	return created.ID, err
+}
+
+func (s *ServerTest) TestVolumes(c *C) {
+	created, err := s.docker.VolumeCreate(context.Background(), volume.VolumesCreateBody{
+		Name: "data", Labels: map[string]string{"dockertest": "1"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(created.Name, Equals, "data")
+	c.Assert(created.Driver, Equals, "local")
+
+	anonymous, err := s.docker.VolumeCreate(context.Background(), volume.VolumesCreateBody{})
+	c.Assert(err, IsNil)
+	c.Assert(anonymous.Name, HasLen, 64)
+
+	_, err = s.docker.VolumeCreate(context.Background(), volume.VolumesCreateBody{Driver: "nfs"})
+	c.Assert(err, ErrorMatches, ".*plugin \"nfs\" not found.*")
+
+	args := filters.NewArgs()
+	args.Add("label", "dockertest=1")
+	listed, err := s.docker.VolumeList(context.Background(), args)
+	c.Assert(err, IsNil)
+	c.Assert(listed.Volumes, HasLen, 1)
+	c.Assert(listed.Volumes[0].Name, Equals, "data")
+
+	inspected, err := s.docker.VolumeInspect(context.Background(), "data")
+	c.Assert(err, IsNil)
+	c.Assert(inspected.Labels, DeepEquals, map[string]string{"dockertest": "1"})
+
+	c.Assert(s.docker.VolumeRemove(context.Background(), "data", false), IsNil)
+	_, err = s.docker.VolumeInspect(context.Background(), "data")
+	c.Assert(err, ErrorMatches, ".*No such volume.*")
+	c.Assert(s.docker.VolumeRemove(context.Background(), "data", false), ErrorMatches, ".*No such volume.*")
+	c.Assert(s.docker.VolumeRemove(context.Background(), "data", true), IsNil)
+}
+
+func (s *ServerTest) TestContainerMounts(c *C) {
+	s.server.AddImage("test", &Image{})
+	id, err := s.createWithMounts(c,
+		mount.Mount{Type: mount.TypeBind, Source: c.MkDir(), Target: "/fixtures", ReadOnly: true},
+		mount.Mount{Type: mount.TypeVolume, Source: "data", Target: "/data",
+			VolumeOptions: &mount.VolumeOptions{Labels: map[string]string{"dockertest": "1"}}},
+		mount.Mount{Type: mount.TypeVolume, Target: "/anonymous"},
+		mount.Mount{Type: mount.TypeTmpfs, Target: "/var/lib/data",
+			TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 1 << 20}},
+	)
+	c.Assert(err, IsNil)
+
+	inspection, err := s.docker.ContainerInspect(context.Background(), id)
+	c.Assert(err, IsNil)
+	c.Assert(inspection.Mounts, HasLen, 4)
+	c.Assert(inspection.Mounts[0].RW, Equals, false)
+	c.Assert(inspection.Mounts[1].Name, Equals, "data")
+	c.Assert(inspection.Mounts[2].Name, HasLen, 64)
+	c.Assert(inspection.Mounts[3].Type, Equals, mount.TypeTmpfs)
+	c.Assert(inspection.HostConfig.Mounts[3].TmpfsOptions.SizeBytes, Equals, int64(1<<20))
+
+	// Mount targets exist inside of the container.
+	stat, err := s.docker.ContainerStatPath(context.Background(), id, "/var/lib/data")
+	c.Assert(err, IsNil)
+	c.Assert(stat.Mode.IsDir(), Equals, true)
+
+	inspected, err := s.docker.VolumeInspect(context.Background(), "data")
+	c.Assert(err, IsNil)
+	c.Assert(inspected.Labels, DeepEquals, map[string]string{"dockertest": "1"})
+	err = s.docker.VolumeRemove(context.Background(), "data", true)
+	c.Assert(err, ErrorMatches, ".*volume is in use.*")
+
+	// Removing the container with RemoveVolumes only deletes anonymous
+	// volumes.
+	err = s.docker.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{RemoveVolumes: true})
+	c.Assert(err, IsNil)
+	listed, err := s.docker.VolumeList(context.Background(), filters.NewArgs())
+	c.Assert(err, IsNil)
+	c.Assert(listed.Volumes, HasLen, 1)
+	c.Assert(listed.Volumes[0].Name, Equals, "data")
+	c.Assert(s.docker.VolumeRemove(context.Background(), "data", false), IsNil)
+}
+
+func (s *ServerTest) TestContainerMountErrors(c *C) {
+	s.server.AddImage("test", &Image{})
+	for _, test := range []struct {
+		mount mount.Mount
+		err   string
+	}{
+		{mount.Mount{Type: mount.TypeTmpfs}, ".*Target must not be empty.*"},
+		{mount.Mount{Type: mount.TypeTmpfs, Target: "relative"}, ".*must be absolute.*"},
+		{mount.Mount{Type: mount.TypeTmpfs, Source: "data", Target: "/data"}, ".*Source must not be specified.*"},
+		{mount.Mount{Type: mount.TypeBind, Target: "/data"}, ".*Source must not be empty.*"},
+		{mount.Mount{Type: mount.TypeBind, Source: "/does/not/exist", Target: "/data"}, ".*bind source path does not exist.*"},
+		{mount.Mount{Type: mount.TypeVolume, Target: "/data", TmpfsOptions: &mount.TmpfsOptions{}}, ".*only VolumeOptions.*"},
+		{mount.Mount{Type: "cifs", Target: "/data"}, ".*mount type unknown.*"},
+	} {
+		_, err := s.createWithMounts(c, test.mount)
+		c.Assert(err, ErrorMatches, test.err)
+	}
+
+	_, err := s.createWithMounts(c,
+		mount.Mount{Type: mount.TypeVolume, Source: "data", Target: "/data"},
+		mount.Mount{Type: mount.TypeTmpfs, Target: "/data/"})
+	c.Assert(err, ErrorMatches, ".*Duplicate mount point: /data.*")
+
+	// Volumes are not created for rejected containers.
+	listed, err := s.docker.VolumeList(context.Background(), filters.NewArgs())
+	c.Assert(err, IsNil)
+	c.Assert(listed.Volumes, HasLen, 0)
+}
@@ -0,0 +1,179 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// Health statuses reported by Docker for containers with a healthcheck.
+const (
+	HealthStarting  = types.Starting
+	HealthHealthy   = types.Healthy
+	HealthUnhealthy = types.Unhealthy
+)
+
+// healthPollInterval is how often WaitForHealth checks the health of
+// the container.
+const healthPollInterval = time.Millisecond * 100
+
+var (
+	// ErrNoHealthcheck is returned by Health and WaitForHealth if the
+	// container does not have a healthcheck.
+	ErrNoHealthcheck = errors.New("container does not have a healthcheck")
+
+	// ErrInvalidHealthcheck is returned by RunContainer if the
+	// Healthcheck on ClientInput is invalid.
+	ErrInvalidHealthcheck = errors.New("invalid healthcheck")
+)
+
+// Healthcheck configures the command Docker runs to check that a
+// container is healthy. Containers use the image's HEALTHCHECK, if it
+// has one, unless a Healthcheck is provided.
+type Healthcheck struct {
+	// Test is the command to run. It is run directly unless the first
+	// element is "CMD-SHELL", in which case the second element is run
+	// using the container's shell. []string{"NONE"} disables the
+	// image's healthcheck.
+	Test []string
+
+	// Interval is the time between checks and Timeout is how long a
+	// check may run before it is considered to have failed. Docker
+	// uses 30 seconds for both if they are not set.
+	Interval time.Duration
+	Timeout  time.Duration
+
+	// StartPeriod gives the container time to start, failures during
+	// this period do not count towards Retries.
+	StartPeriod time.Duration
+
+	// Retries is the number of consecutive failures needed for the
+	// container to be unhealthy. Docker uses 3 if not set.
+	Retries int
+}
+
+// NewHealthcheck produces a *Healthcheck which runs cmd directly inside
+// of the container.
+func NewHealthcheck(cmd ...string) *Healthcheck {
+	return &Healthcheck{Test: cmd}
+}
+
+// HealthConfig converts *Healthcheck into the value which may be used
+// for the Healthcheck field of container.Config.
+func (h *Healthcheck) HealthConfig() *container.HealthConfig {
+	if h == nil {
+		return nil
+	}
+	test := h.Test
+	if len(test) > 0 {
+		switch test[0] {
+		case "CMD", "CMD-SHELL", "NONE":
+		default:
+			test = append([]string{"CMD"}, test...)
+		}
+	}
+	return &container.HealthConfig{
+		Test:        test,
+		Interval:    h.Interval,
+		Timeout:     h.Timeout,
+		StartPeriod: h.StartPeriod,
+		Retries:     h.Retries,
+	}
+}
+
+// validate checks the healthcheck using the same rules as Docker.
+func (h *Healthcheck) validate() error {
+	if h == nil {
+		return nil
+	}
+	if len(h.Test) == 0 {
+		return fmt.Errorf("%w: test command must be provided", ErrInvalidHealthcheck)
+	}
+	for name, value := range map[string]time.Duration{
+		"interval": h.Interval, "timeout": h.Timeout, "start period": h.StartPeriod,
+	} {
+		if value != 0 && value < time.Millisecond {
+			return fmt.Errorf("%w: %s must be at least 1ms", ErrInvalidHealthcheck, name)
+		}
+	}
+	if h.Retries < 0 {
+		return fmt.Errorf("%w: retries must not be negative", ErrInvalidHealthcheck)
+	}
+	return nil
+}
+
+// HealthProbe is the result of a single run of a container's
+// healthcheck.
+type HealthProbe struct {
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+	Output   string
+}
+
+// Health describes the health of a container.
+type Health struct {
+	// Status is one of HealthStarting, HealthHealthy or
+	// HealthUnhealthy.
+	Status string
+
+	// FailingStreak is the number of consecutive failed probes.
+	FailingStreak int
+
+	// Log contains the most recent probes, oldest first. Docker keeps
+	// the last five.
+	Log []HealthProbe
+}
+
+// Health returns the health of the container as of the last time this
+// struct was refreshed. ErrNoHealthcheck is returned if the container
+// does not have a healthcheck.
+func (c *ContainerInfo) Health() (*Health, error) {
+	if c.JSON.ContainerJSONBase == nil || c.JSON.State == nil || c.JSON.State.Health == nil {
+		return nil, ErrNoHealthcheck
+	}
+	health := &Health{
+		Status:        c.JSON.State.Health.Status,
+		FailingStreak: c.JSON.State.Health.FailingStreak,
+		Log:           []HealthProbe{},
+	}
+	for _, result := range c.JSON.State.Health.Log {
+		if result != nil {
+			health.Log = append(health.Log, HealthProbe{
+				Start: result.Start, End: result.End, ExitCode: result.ExitCode, Output: result.Output,
+			})
+		}
+	}
+	return health, nil
+}
+
+// WaitForHealth blocks until the container is either healthy or
+// unhealthy and returns its health, the ContainerInfo is refreshed while
+// waiting. An error wrapping ErrContainerExited is returned if the
+// container stops first.
+func (c *ContainerInfo) WaitForHealth(ctx context.Context) (*Health, error) {
+	for {
+		if err := c.RefreshContext(ctx); err != nil {
+			return nil, err
+		}
+		health, err := c.Health()
+		if err != nil {
+			return nil, err
+		}
+		if health.Status == HealthHealthy || health.Status == HealthUnhealthy {
+			return health, nil
+		}
+		if !c.JSON.State.Running {
+			return health, fmt.Errorf("%w with code %d", ErrContainerExited, c.JSON.State.ExitCode)
+		}
+		select {
+		case <-ctx.Done():
+			return health, ctx.Err()
+		case <-time.After(healthPollInterval):
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type HealthTest struct{}
+
+var _ = Suite(&HealthTest{})
+
+func (s *HealthTest) TestHealthConfig(c *C) {
+	var check *Healthcheck
+	c.Assert(check.HealthConfig(), IsNil)
+
+	check = NewHealthcheck("redis-cli", "ping")
+	check.Interval = time.Second
+	check.Retries = 2
+	c.Assert(check.HealthConfig(), DeepEquals, &container.HealthConfig{
+		Test: []string{"CMD", "redis-cli", "ping"}, Interval: time.Second, Retries: 2,
+	})
+	for _, test := range [][]string{{"CMD-SHELL", "true"}, {"CMD", "true"}, {"NONE"}} {
+		c.Assert(NewHealthcheck(test...).HealthConfig().Test, DeepEquals, test)
+	}
+}
+
+func (s *HealthTest) TestValidate(c *C) {
+	var check *Healthcheck
+	c.Assert(check.validate(), IsNil)
+	c.Assert(NewHealthcheck("true").validate(), IsNil)
+
+	for _, check := range []*Healthcheck{
+		{},
+		{Test: []string{"true"}, Interval: time.Microsecond},
+		{Test: []string{"true"}, Timeout: time.Microsecond},
+		{Test: []string{"true"}, StartPeriod: time.Microsecond},
+		{Test: []string{"true"}, Retries: -1},
+	} {
+		c.Assert(errors.Is(check.validate(), ErrInvalidHealthcheck), Equals, true)
+	}
+}
+
+func (s *HealthTest) TestHealthNoHealthcheck(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	_, err := info.Health()
+	c.Assert(err, Equals, ErrNoHealthcheck)
+	_, err = info.WaitForHealth(context.Background())
+	c.Assert(err, Equals, ErrNoHealthcheck)
+}
+
+func (s *HealthTest) TestWaitForHealth(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{
+		Exec: func(exec *fakeengine.Exec) int {
+			fmt.Fprint(exec.Stdout, "PONG") // nolint: errcheck
+			return 0
+		},
+	})
+	input := NewClientInput(testImage)
+	input.Healthcheck = NewHealthcheck("redis-cli", "ping")
+	input.Healthcheck.Interval = time.Millisecond * 10
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	health, err := info.Health()
+	c.Assert(err, IsNil)
+	c.Assert(health.Status, Equals, HealthStarting)
+
+	health, err = info.WaitForHealth(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(health.Status, Equals, HealthHealthy)
+	c.Assert(health.FailingStreak, Equals, 0)
+	c.Assert(len(health.Log) > 0, Equals, true)
+	c.Assert(health.Log[0].ExitCode, Equals, 0)
+	c.Assert(health.Log[0].Output, Equals, "PONG")
+}
+
+func (s *HealthTest) TestWaitForHealthUnhealthy(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{Config: container.Config{
+		Healthcheck: &container.HealthConfig{
+			Test: []string{"CMD-SHELL", "false"}, Interval: time.Millisecond * 10, Retries: 2,
+		},
+	}})
+	info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+
+	health, err := info.WaitForHealth(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(health.Status, Equals, HealthUnhealthy)
+	c.Assert(health.FailingStreak >= 2, Equals, true)
+}
+
+func (s *HealthTest) TestWaitForHealthExited(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	input := NewClientInput(testImage)
+	input.Healthcheck = NewHealthcheck("true")
+	input.Healthcheck.Interval = time.Hour
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(server.Exit(info.ID(), 2), IsNil)
+
+	_, err = info.WaitForHealth(context.Background())
+	c.Assert(errors.Is(err, ErrContainerExited), Equals, true)
+	c.Assert(err, ErrorMatches, ".*with code 2")
+}
+
+func (s *HealthTest) TestWaitForHealthContext(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	input := NewClientInput(testImage)
+	input.Healthcheck = NewHealthcheck("true")
+	input.Healthcheck.Interval = time.Hour
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	health, err := info.WaitForHealth(ctx)
+	c.Assert(err, NotNil)
+	c.Assert(health.Status, Equals, HealthStarting)
+}
+
+func (s *HealthTest) TestRunContainerInvalidHealthcheck(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	input := NewClientInput(testImage)
+	input.Healthcheck = &Healthcheck{}
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(errors.Is(err, ErrInvalidHealthcheck), Equals, true)
+}
@@ -0,0 +1,102 @@
+package dockertest
+
+import (
+	"context"
+	"time"
+)
+
+// stopContext returns a context for a call to the engine which may wait
+// up to timeout for a container to stop. If the client was configured
+// with a timeout the returned context is bounded by it plus timeout.
+func (d *DockerClient) stopContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if d.timeout > 0 {
+		return context.WithTimeout(ctx, d.timeout+timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// StopContainer sends the Container its stop signal, normally SIGTERM,
+// and kills it if it has not exited after timeout. Stopping a Container
+// which is not running is not an error.
+func (d *DockerClient) StopContainer(ctx context.Context, id string, timeout time.Duration) error {
+	ctx, cancel := d.stopContext(ctx, timeout)
+	defer cancel()
+	return d.docker.ContainerStop(ctx, id, &timeout)
+}
+
+// KillContainer sends a signal, such as "SIGHUP" or "SIGKILL", to the
+// Container. SIGKILL is sent if signal is empty.
+func (d *DockerClient) KillContainer(ctx context.Context, id string, signal string) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	return d.docker.ContainerKill(ctx, id, signal)
+}
+
+// PauseContainer suspends every process in the Container.
+func (d *DockerClient) PauseContainer(ctx context.Context, id string) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	return d.docker.ContainerPause(ctx, id)
+}
+
+// UnpauseContainer resumes the processes in a paused Container.
+func (d *DockerClient) UnpauseContainer(ctx context.Context, id string) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	return d.docker.ContainerUnpause(ctx, id)
+}
+
+// RestartContainer stops the Container, as StopContainer does, then
+// starts it again. A Container which is not running is started.
+func (d *DockerClient) RestartContainer(ctx context.Context, id string, timeout time.Duration) error {
+	ctx, cancel := d.stopContext(ctx, timeout)
+	defer cancel()
+	return d.docker.ContainerRestart(ctx, id, &timeout)
+}
+
+// Stop gracefully stops the Container, see DockerClient.StopContainer,
+// then refreshes the data present on this struct.
+func (c *ContainerInfo) Stop(ctx context.Context, timeout time.Duration) error {
+	if err := c.client.StopContainer(ctx, c.ID(), timeout); err != nil {
+		return err
+	}
+	return c.RefreshContext(ctx)
+}
+
+// Kill sends a signal to the Container, see DockerClient.KillContainer,
+// then refreshes the data present on this struct.
+func (c *ContainerInfo) Kill(ctx context.Context, signal string) error {
+	if err := c.client.KillContainer(ctx, c.ID(), signal); err != nil {
+		return err
+	}
+	return c.RefreshContext(ctx)
+}
+
+// Pause suspends the Container then refreshes the data present on this
+// struct.
+func (c *ContainerInfo) Pause(ctx context.Context) error {
+	if err := c.client.PauseContainer(ctx, c.ID()); err != nil {
+		return err
+	}
+	return c.RefreshContext(ctx)
+}
+
+// Unpause resumes the Container then refreshes the data present on this
+// struct.
+func (c *ContainerInfo) Unpause(ctx context.Context) error {
+	if err := c.client.UnpauseContainer(ctx, c.ID()); err != nil {
+		return err
+	}
+	return c.RefreshContext(ctx)
+}
+
+// Restart restarts the Container, see DockerClient.RestartContainer, then
+// refreshes the data present on this struct. Docker may publish random
+// ports on different host ports after a restart so ports returned by
+// Port before calling Restart should not be reused.
+func (c *ContainerInfo) Restart(ctx context.Context, timeout time.Duration) error {
+	if err := c.client.RestartContainer(ctx, c.ID(), timeout); err != nil {
+		return err
+	}
+	return c.RefreshContext(ctx)
+}
@@ -0,0 +1,106 @@
+package dockertest
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type LifecycleTest struct{}
+
+var _ = Suite(&LifecycleTest{})
+
+func (s *LifecycleTest) TestStop(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	c.Assert(info.Stop(context.Background(), time.Second), IsNil)
+	c.Assert(info.JSON.State.Running, Equals, false)
+	c.Assert(info.ExitCode(), Equals, 143)
+	signals, err := server.Signals(info.ID())
+	c.Assert(err, IsNil)
+	c.Assert(signals, DeepEquals, []string{"SIGTERM"})
+
+	// Stopping a stopped container is not an error.
+	c.Assert(info.Stop(context.Background(), time.Second), IsNil)
+}
+
+func (s *LifecycleTest) TestKill(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{HandledSignals: []string{"SIGHUP"}})
+
+	c.Assert(info.Kill(context.Background(), "SIGHUP"), IsNil)
+	c.Assert(info.JSON.State.Running, Equals, true)
+	c.Assert(info.Kill(context.Background(), ""), IsNil)
+	c.Assert(info.JSON.State.Running, Equals, false)
+	c.Assert(info.ExitCode(), Equals, 137)
+	c.Assert(info.Kill(context.Background(), ""), ErrorMatches, ".*is not running")
+}
+
+func (s *LifecycleTest) TestPauseUnpause(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	c.Assert(info.Pause(context.Background()), IsNil)
+	c.Assert(info.JSON.State.Paused, Equals, true)
+	c.Assert(info.Data.State, Equals, "paused")
+	c.Assert(info.Pause(context.Background()), ErrorMatches, ".*already paused")
+	c.Assert(info.Unpause(context.Background()), IsNil)
+	c.Assert(info.JSON.State.Paused, Equals, false)
+	c.Assert(info.Data.State, Equals, "running")
+}
+
+func (s *LifecycleTest) TestRestart(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	input := NewClientInput(testImage)
+	input.Ports.Add(&Port{Private: 80, Public: RandomPort, Protocol: ProtocolTCP})
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	before, err := info.Port(80)
+	c.Assert(err, IsNil)
+
+	c.Assert(info.Restart(context.Background(), time.Second), IsNil)
+	c.Assert(info.JSON.State.Running, Equals, true)
+	after, err := info.Port(80)
+	c.Assert(err, IsNil)
+	c.Assert(after.Public, Not(Equals), before.Public)
+	c.Assert(after.Public, Not(Equals), RandomPort)
+}
+
+func (s *LifecycleTest) TestStopSignal(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{HandledSignals: []string{"SIGQUIT"}})
+	input := NewClientInput(testImage)
+	input.ModifyConfig = func(config *container.Config, hostConfig *container.HostConfig) {
+		config.StopSignal = "SIGQUIT"
+	}
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	// The container ignores its stop signal so it is killed.
+	c.Assert(info.Stop(context.Background(), 0), IsNil)
+	c.Assert(info.ExitCode(), Equals, 137)
+	signals, err := server.Signals(info.ID())
+	c.Assert(err, IsNil)
+	c.Assert(signals, DeepEquals, []string{"SIGQUIT", "SIGKILL"})
+}
+
+func (s *LifecycleTest) TestNotFound(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+	c.Assert(dc.RemoveContainer(context.Background(), info.ID()), IsNil)
+
+	c.Assert(info.Stop(context.Background(), time.Second), ErrorMatches, ".*No such container.*")
+	c.Assert(info.Restart(context.Background(), time.Second), ErrorMatches, ".*No such container.*")
+	c.Assert(info.Pause(context.Background()), ErrorMatches, ".*No such container.*")
+}
@@ -0,0 +1,308 @@
+package dockertest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Names of the streams used by LogLine.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// LogOptions provides optional settings for retrieving the logs of a
+// container.
+type LogOptions struct {
+	// Since and Until, if set, limit the logs to those produced within
+	// the given time range. The API version this package targets has
+	// no until parameter so Until is applied as the logs are read and
+	// Follow stops following at Until.
+	Since time.Time
+	Until time.Time
+
+	// Tail limits the output to the given number of lines from the end
+	// of the logs, before Until if it is set. All lines are returned
+	// when Tail is zero.
+	Tail int
+
+	// Timestamps prefixes each line with the time it was produced.
+	Timestamps bool
+}
+
+// containerLogsOptions converts *LogOptions into the options which may
+// be passed to the ContainerLogs() API call.
+func (o *LogOptions) containerLogsOptions(follow bool) types.ContainerLogsOptions {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     follow,
+		Timestamps: o.Timestamps || !o.Until.IsZero(),
+	}
+	if !o.Since.IsZero() {
+		options.Since = formatTimestamp(o.Since)
+	}
+	// With Until the last lines before Until are wanted, not the last
+	// lines overall, so Tail is applied by copyLogs instead.
+	if o.Tail > 0 && o.Until.IsZero() {
+		options.Tail = strconv.Itoa(o.Tail)
+	}
+	return options
+}
+
+// formatTimestamp converts t to the seconds.nanoseconds format used by
+// the logs API.
+func formatTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// LogOutput contains the demultiplexed output of a container. Docker
+// merges stderr into stdout for containers using a TTY.
+type LogOutput struct {
+	Stdout string
+	Stderr string
+}
+
+// LogLine is a single line of output produced by a container.
+type LogLine struct {
+	// Stream is either StreamStdout or StreamStderr.
+	Stream string
+
+	// Text is the line without its trailing newline.
+	Text string
+}
+
+// Logs returns the output the container has produced so far. The options
+// argument may be nil.
+func (c *ContainerInfo) Logs(ctx context.Context, options *LogOptions) (*LogOutput, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx, cancel := c.client.callContext(ctx)
+	defer cancel()
+	if err := c.copyLogs(ctx, options, false, stdout, stderr); err != nil {
+		return nil, err
+	}
+	return &LogOutput{Stdout: stdout.String(), Stderr: stderr.String()}, nil
+}
+
+// Follow copies the output of the container to stdout and stderr as it
+// is produced. It returns once the container stops, ctx is done or Until
+// is reached, none of which is considered an error. The options argument
+// may be nil.
+func (c *ContainerInfo) Follow(ctx context.Context, options *LogOptions, stdout io.Writer, stderr io.Writer) error {
+	follow := true
+	if options != nil && !options.Until.IsZero() {
+		// Nothing produced after Until is wanted so there is nothing to
+		// follow once it has passed.
+		if time.Now().Before(options.Until) {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, options.Until)
+			defer cancel()
+		} else {
+			follow = false
+		}
+	}
+	err := c.copyLogs(ctx, options, follow, stdout, stderr)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// FollowLines behaves like Follow but sends each line of output to lines.
+// The lines channel is not closed.
+func (c *ContainerInfo) FollowLines(ctx context.Context, options *LogOptions, lines chan<- LogLine) error {
+	stdout := &lineWriter{ctx: ctx, stream: StreamStdout, lines: lines}
+	stderr := &lineWriter{ctx: ctx, stream: StreamStderr, lines: lines}
+	if err := c.Follow(ctx, options, stdout, stderr); err != nil {
+		return err
+	}
+	stdout.flush()
+	stderr.flush()
+	return nil
+}
+
+func (c *ContainerInfo) copyLogs(ctx context.Context, options *LogOptions, follow bool, stdout io.Writer, stderr io.Writer) error {
+	if options == nil {
+		options = &LogOptions{}
+	}
+	reader, err := c.client.docker.ContainerLogs(ctx, c.ID(), options.containerLogsOptions(follow))
+	if err != nil {
+		return err
+	}
+	defer reader.Close() // nolint: errcheck
+
+	if options.Until.IsZero() {
+		return c.copyStreams(reader, stdout, stderr)
+	}
+
+	var tail *tailWriter
+	if options.Tail > 0 {
+		tail = &tailWriter{size: options.Tail, stdout: stdout, stderr: stderr}
+		stdout, stderr = tail.stream(false), tail.stream(true)
+	}
+	stdoutUntil := &untilWriter{writer: stdout, until: options.Until, timestamps: options.Timestamps}
+	stderrUntil := &untilWriter{writer: stderr, until: options.Until, timestamps: options.Timestamps}
+	err = c.copyStreams(reader, stdoutUntil, stderrUntil)
+	stdoutUntil.flush()
+	stderrUntil.flush()
+	if tail != nil {
+		// The lines kept are written even if following was interrupted.
+		if tailErr := tail.flush(); err == nil {
+			err = tailErr
+		}
+	}
+	return err
+}
+
+// copyStreams copies the logs read from reader to stdout and stderr.
+func (c *ContainerInfo) copyStreams(reader io.Reader, stdout io.Writer, stderr io.Writer) error {
+	if c.JSON.Config != nil && c.JSON.Config.Tty {
+		_, err := io.Copy(stdout, reader)
+		return err
+	}
+	_, err := stdcopy.StdCopy(stdout, stderr, reader)
+	return err
+}
+
+// tailWriter keeps the last size lines written to the writers returned
+// by stream, across both streams, and writes them to stdout and stderr
+// when flushed. Each write must be a single line.
+type tailWriter struct {
+	size   int
+	stdout io.Writer
+	stderr io.Writer
+	lines  []tailLine
+}
+
+type tailLine struct {
+	stderr bool
+	data   []byte
+}
+
+// stream returns the io.Writer for stdout or stderr.
+func (w *tailWriter) stream(stderr bool) io.Writer {
+	return tailStream{tail: w, stderr: stderr}
+}
+
+// flush writes the lines which were kept.
+func (w *tailWriter) flush() error {
+	for _, line := range w.lines {
+		writer := w.stdout
+		if line.stderr {
+			writer = w.stderr
+		}
+		if _, err := writer.Write(line.data); err != nil {
+			return err
+		}
+	}
+	w.lines = nil
+	return nil
+}
+
+type tailStream struct {
+	tail   *tailWriter
+	stderr bool
+}
+
+func (s tailStream) Write(data []byte) (int, error) {
+	line := tailLine{stderr: s.stderr, data: append([]byte{}, data...)}
+	s.tail.lines = append(s.tail.lines, line)
+	if len(s.tail.lines) > s.tail.size {
+		s.tail.lines = s.tail.lines[1:]
+	}
+	return len(data), nil
+}
+
+// untilWriter is an io.Writer which drops lines produced after until.
+// Each line written to it must start with a timestamp which is removed
+// unless timestamps is true.
+type untilWriter struct {
+	writer     io.Writer
+	until      time.Time
+	timestamps bool
+	buffer     bytes.Buffer
+}
+
+func (w *untilWriter) Write(data []byte) (int, error) {
+	w.buffer.Write(data) // nolint: errcheck
+	for {
+		index := bytes.IndexByte(w.buffer.Bytes(), '\n')
+		if index < 0 {
+			return len(data), nil
+		}
+		if err := w.write(w.buffer.Next(index + 1)); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// flush writes any remaining partial line.
+func (w *untilWriter) flush() {
+	if w.buffer.Len() > 0 {
+		w.write(w.buffer.Next(w.buffer.Len())) // nolint: errcheck
+	}
+}
+
+func (w *untilWriter) write(line []byte) error {
+	index := bytes.IndexByte(line, ' ')
+	if index > 0 {
+		produced, err := time.Parse(time.RFC3339Nano, string(line[:index]))
+		if err == nil {
+			if produced.After(w.until) {
+				return nil
+			}
+			if !w.timestamps {
+				line = line[index+1:]
+			}
+		}
+	}
+	_, err := w.writer.Write(line)
+	return err
+}
+
+// lineWriter is an io.Writer which splits the data written to it into
+// lines and sends them to a channel.
+type lineWriter struct {
+	ctx    context.Context
+	stream string
+	lines  chan<- LogLine
+	buffer bytes.Buffer
+}
+
+func (w *lineWriter) Write(data []byte) (int, error) {
+	w.buffer.Write(data) // nolint: errcheck
+	for {
+		index := bytes.IndexByte(w.buffer.Bytes(), '\n')
+		if index < 0 {
+			return len(data), nil
+		}
+		line := string(w.buffer.Next(index + 1))
+		if err := w.send(line[:len(line)-1]); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// flush sends any remaining partial line.
+func (w *lineWriter) flush() {
+	if w.buffer.Len() > 0 {
+		w.send(w.buffer.String()) // nolint: errcheck
+		w.buffer.Reset()
+	}
+}
+
+func (w *lineWriter) send(text string) error {
+	select {
+	case w.lines <- LogLine{Stream: w.stream, Text: text}:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
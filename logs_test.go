@@ -0,0 +1,175 @@
+package dockertest
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type LogsTest struct{}
+
+var _ = Suite(&LogsTest{})
+
+func (s *LogsTest) TestContainerLogsOptions(c *C) {
+	since := time.Unix(100, 5)
+	options := (&LogOptions{Since: since, Tail: 3}).containerLogsOptions(true)
+	c.Assert(options.Since, Equals, "100.000000005")
+	c.Assert(options.Tail, Equals, "3")
+	c.Assert(options.Follow, Equals, true)
+	c.Assert(options.Timestamps, Equals, false)
+
+	options = (&LogOptions{Until: since}).containerLogsOptions(false)
+	c.Assert(options.Tail, Equals, "")
+	c.Assert(options.Timestamps, Equals, true)
+
+	// Tail is applied after Until by copyLogs.
+	options = (&LogOptions{Until: since, Tail: 3}).containerLogsOptions(false)
+	c.Assert(options.Tail, Equals, "")
+}
+
+func (s *LogsTest) TestTailWriter(c *C) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	tail := &tailWriter{size: 2, stdout: stdout, stderr: stderr}
+	for _, line := range []string{"one\n", "two\n", "three\n"} {
+		_, err := tail.stream(false).Write([]byte(line))
+		c.Assert(err, IsNil)
+	}
+	_, err := tail.stream(true).Write([]byte("error\n"))
+	c.Assert(err, IsNil)
+	c.Assert(stdout.Len()+stderr.Len(), Equals, 0)
+	c.Assert(tail.flush(), IsNil)
+	c.Assert(stdout.String(), Equals, "three\n")
+	c.Assert(stderr.String(), Equals, "error\n")
+}
+
+func (s *LogsTest) TestUntilWriter(c *C) {
+	until := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	buffer := &bytes.Buffer{}
+	writer := &untilWriter{writer: buffer, until: until}
+	_, err := writer.Write([]byte("2017-12-31T23:59:59.5Z one\n2018-01-01T00:00:01Z tw"))
+	c.Assert(err, IsNil)
+	_, err = writer.Write([]byte("o\n2017-01-01T00:00:00Z three"))
+	c.Assert(err, IsNil)
+	writer.flush()
+	c.Assert(buffer.String(), Equals, "one\nthree")
+
+	buffer.Reset()
+	writer = &untilWriter{writer: buffer, until: until, timestamps: true}
+	_, err = writer.Write([]byte("2017-12-31T23:59:59Z one\n"))
+	c.Assert(err, IsNil)
+	c.Assert(buffer.String(), Equals, "2017-12-31T23:59:59Z one\n")
+}
+
+func (s *LogsTest) TestLogs(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{
+		Stdout: []string{"one", "two"},
+		Stderr: []string{"error"},
+	})
+
+	output, err := info.Logs(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(output, DeepEquals, &LogOutput{Stdout: "one\ntwo\n", Stderr: "error\n"})
+
+	output, err = info.Logs(context.Background(), &LogOptions{Tail: 1, Timestamps: true})
+	c.Assert(err, IsNil)
+	c.Assert(output.Stdout+output.Stderr, Matches, `\S+Z error\n`)
+
+	output, err = info.Logs(context.Background(), &LogOptions{Since: time.Now().Add(time.Hour)})
+	c.Assert(err, IsNil)
+	c.Assert(output, DeepEquals, &LogOutput{})
+
+	output, err = info.Logs(context.Background(), &LogOptions{Until: time.Now().Add(-time.Hour)})
+	c.Assert(err, IsNil)
+	c.Assert(output, DeepEquals, &LogOutput{})
+}
+
+func (s *LogsTest) TestLogsTailUntil(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{Stdout: []string{"one", "two"}})
+	time.Sleep(time.Millisecond * 10)
+	until := time.Now()
+	time.Sleep(time.Millisecond * 10)
+	c.Assert(server.WriteStdout(info.ID(), "three"), IsNil)
+
+	output, err := info.Logs(context.Background(), &LogOptions{Tail: 1, Until: until})
+	c.Assert(err, IsNil)
+	c.Assert(output, DeepEquals, &LogOutput{Stdout: "two\n"})
+}
+
+func (s *LogsTest) TestLogsContainerRemoved(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+	c.Assert(dc.RemoveContainer(context.Background(), info.ID()), IsNil)
+	_, err := info.Logs(context.Background(), nil)
+	c.Assert(err, ErrorMatches, ".*No such container.*")
+}
+
+func (s *LogsTest) TestFollow(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{Stdout: []string{"one"}})
+
+	go func() {
+		c.Check(server.WriteStderr(info.ID(), "two"), IsNil)
+		c.Check(server.Exit(info.ID(), 0), IsNil)
+	}()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	c.Assert(info.Follow(context.Background(), nil, stdout, stderr), IsNil)
+	c.Assert(stdout.String(), Equals, "one\n")
+	c.Assert(stderr.String(), Equals, "two\n")
+}
+
+func (s *LogsTest) TestFollowContextDone(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{Stdout: []string{"one"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	stdout := &bytes.Buffer{}
+	c.Assert(info.Follow(ctx, nil, stdout, stdout), IsNil)
+	c.Assert(stdout.String(), Equals, "one\n")
+}
+
+func (s *LogsTest) TestFollowUntil(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{Stdout: []string{"one"}})
+
+	// Following stops at Until even though the container is running.
+	stdout := &bytes.Buffer{}
+	options := &LogOptions{Until: time.Now().Add(time.Millisecond * 50)}
+	c.Assert(info.Follow(context.Background(), options, stdout, stdout), IsNil)
+	c.Assert(stdout.String(), Equals, "one\n")
+	c.Assert(time.Now().Before(options.Until), Equals, false)
+
+	// An Until in the past returns the earlier lines without waiting.
+	c.Assert(server.WriteStdout(info.ID(), "two"), IsNil)
+	stdout.Reset()
+	c.Assert(info.Follow(context.Background(), options, stdout, stdout), IsNil)
+	c.Assert(stdout.String(), Equals, "one\n")
+}
+
+func (s *LogsTest) TestFollowLines(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{Stdout: []string{"one"}})
+
+	lines := make(chan LogLine)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- info.FollowLines(context.Background(), nil, lines)
+	}()
+	c.Assert(<-lines, DeepEquals, LogLine{Stream: StreamStdout, Text: "one"})
+	c.Assert(server.WriteStderr(info.ID(), "two"), IsNil)
+	c.Assert(<-lines, DeepEquals, LogLine{Stream: StreamStderr, Text: "two"})
+	c.Assert(server.Exit(info.ID(), 0), IsNil)
+	c.Assert(<-errs, IsNil)
+}
@@ -0,0 +1,118 @@
+package dockertest
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/mount"
+)
+
+// MountType is the kind of filesystem a Mount provides.
+type MountType string
+
+const (
+	// MountBind mounts a file or directory from the host.
+	MountBind MountType = "bind"
+
+	// MountVolume mounts a named or anonymous volume.
+	MountVolume MountType = "volume"
+
+	// MountTmpfs mounts an in-memory filesystem.
+	MountTmpfs MountType = "tmpfs"
+)
+
+var (
+	// ErrMountTargetNotProvided is returned when a Mount does not have
+	// an absolute Target.
+	ErrMountTargetNotProvided = errors.New("mount target must be an absolute path")
+
+	// ErrMountSourceNotProvided is returned when a bind Mount does not
+	// have a Source.
+	ErrMountSourceNotProvided = errors.New("bind mount source not provided")
+
+	// ErrInvalidMount is returned when a Mount has an unknown Type or
+	// options which do not apply to its Type.
+	ErrInvalidMount = errors.New("invalid mount")
+)
+
+// Mount describes a filesystem which is mounted into a container. See
+// BindMount, VolumeMount and TmpfsMount.
+type Mount struct {
+	Type MountType
+
+	// Source is the path on the host for bind mounts, relative paths are
+	// resolved against the current working directory. For volume mounts
+	// it is the name of the volume, which Docker creates if it does not
+	// exist, or "" for an anonymous volume. It is not used by tmpfs.
+	Source string
+
+	// Target is the absolute path inside of the container.
+	Target string
+
+	ReadOnly bool
+
+	// TmpfsSize limits the size of a tmpfs mount in bytes. The size is
+	// unlimited if not set.
+	TmpfsSize int64
+}
+
+// BindMount returns a Mount which makes the host path source available
+// at target inside of the container.
+func BindMount(source string, target string) Mount {
+	return Mount{Type: MountBind, Source: source, Target: target}
+}
+
+// VolumeMount returns a Mount which makes the named volume available at
+// target inside of the container.
+func VolumeMount(name string, target string) Mount {
+	return Mount{Type: MountVolume, Source: name, Target: target}
+}
+
+// TmpfsMount returns a Mount which places an in-memory filesystem, limited
+// to size bytes, at target inside of the container.
+func TmpfsMount(target string, size int64) Mount {
+	return Mount{Type: MountTmpfs, Target: target, TmpfsSize: size}
+}
+
+// mount converts Mount into the form used by the Docker API. Labels are
+// applied to volumes which Docker creates for the mount.
+func (m Mount) mount(labels map[string]string) (mount.Mount, error) {
+	if !path.IsAbs(m.Target) {
+		return mount.Mount{}, ErrMountTargetNotProvided
+	}
+	converted := mount.Mount{
+		Type:     mount.Type(m.Type),
+		Source:   m.Source,
+		Target:   m.Target,
+		ReadOnly: m.ReadOnly,
+	}
+
+	switch m.Type {
+	case MountBind:
+		if m.Source == "" {
+			return mount.Mount{}, ErrMountSourceNotProvided
+		}
+		if m.TmpfsSize != 0 {
+			return mount.Mount{}, ErrInvalidMount
+		}
+		source, err := filepath.Abs(m.Source)
+		if err != nil {
+			return mount.Mount{}, err
+		}
+		converted.Source = source
+	case MountVolume:
+		if m.TmpfsSize != 0 {
+			return mount.Mount{}, ErrInvalidMount
+		}
+		converted.VolumeOptions = &mount.VolumeOptions{Labels: labels}
+	case MountTmpfs:
+		if m.Source != "" || m.TmpfsSize < 0 {
+			return mount.Mount{}, ErrInvalidMount
+		}
+		converted.TmpfsOptions = &mount.TmpfsOptions{SizeBytes: m.TmpfsSize}
+	default:
+		return mount.Mount{}, ErrInvalidMount
+	}
+	return converted, nil
+}
@@ -0,0 +1,111 @@
+package dockertest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type MountTest struct{}
+
+var _ = Suite(&MountTest{})
+
+func (s *MountTest) TestMount(c *C) {
+	labels := map[string]string{"dockertest": "1"}
+	converted, err := TmpfsMount("/data", 1024).mount(labels)
+	c.Assert(err, IsNil)
+	c.Assert(converted, DeepEquals, mount.Mount{
+		Type:         mount.TypeTmpfs,
+		Target:       "/data",
+		TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 1024},
+	})
+
+	converted, err = VolumeMount("data", "/data").mount(labels)
+	c.Assert(err, IsNil)
+	c.Assert(converted, DeepEquals, mount.Mount{
+		Type:          mount.TypeVolume,
+		Source:        "data",
+		Target:        "/data",
+		VolumeOptions: &mount.VolumeOptions{Labels: labels},
+	})
+
+	// Relative bind sources are resolved against the working directory.
+	cwd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	bind := BindMount("fixtures", "/fixtures")
+	bind.ReadOnly = true
+	converted, err = bind.mount(labels)
+	c.Assert(err, IsNil)
+	c.Assert(converted, DeepEquals, mount.Mount{
+		Type:     mount.TypeBind,
+		Source:   filepath.Join(cwd, "fixtures"),
+		Target:   "/fixtures",
+		ReadOnly: true,
+	})
+}
+
+func (s *MountTest) TestMountErrors(c *C) {
+	for _, test := range []struct {
+		mount Mount
+		err   error
+	}{
+		{TmpfsMount("", 0), ErrMountTargetNotProvided},
+		{TmpfsMount("relative", 0), ErrMountTargetNotProvided},
+		{BindMount("", "/data"), ErrMountSourceNotProvided},
+		{Mount{Type: MountBind, Source: "/tmp", Target: "/data", TmpfsSize: 1}, ErrInvalidMount},
+		{Mount{Type: MountVolume, Target: "/data", TmpfsSize: 1}, ErrInvalidMount},
+		{Mount{Type: MountTmpfs, Source: "/tmp", Target: "/data"}, ErrInvalidMount},
+		{TmpfsMount("/data", -1), ErrInvalidMount},
+		{Mount{Type: "cifs", Target: "/data"}, ErrInvalidMount},
+	} {
+		_, err := test.mount.mount(nil)
+		c.Assert(err, Equals, test.err)
+	}
+}
+
+func (s *MountTest) TestRunContainerMounts(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	input := NewClientInput(testImage)
+	input.Mounts = []Mount{
+		BindMount(c.MkDir(), "/fixtures"),
+		VolumeMount("", "/anonymous"),
+		TmpfsMount("/var/lib/data", 1<<20),
+	}
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(info.JSON.Mounts, HasLen, 3)
+	c.Assert(info.JSON.HostConfig.Mounts[2].TmpfsOptions.SizeBytes, Equals, int64(1<<20))
+
+	// Volumes created for the container are labeled so they can be
+	// found and are removed with the container.
+	volumes, err := dc.ListVolumes(context.Background(), NewVolumeInput(""))
+	c.Assert(err, IsNil)
+	c.Assert(volumes, HasLen, 1)
+	c.Assert(volumes[0].Name(), Equals, info.JSON.Mounts[1].Name)
+	c.Assert(dc.RemoveContainer(context.Background(), info.ID()), IsNil)
+	volumes, err = dc.ListVolumes(context.Background(), NewVolumeInput(""))
+	c.Assert(err, IsNil)
+	c.Assert(volumes, HasLen, 0)
+}
+
+func (s *MountTest) TestRunContainerInvalidMount(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	input := NewClientInput(testImage)
+	input.Mounts = []Mount{BindMount("", "/fixtures")}
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, Equals, ErrMountSourceNotProvided)
+
+	input.Mounts = []Mount{BindMount("/does/not/exist", "/fixtures")}
+	_, err = dc.RunContainer(context.Background(), input)
+	c.Assert(err, ErrorMatches, ".*bind source path does not exist.*")
+}
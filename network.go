@@ -0,0 +1,187 @@
+package dockertest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+var (
+	// ErrNetworkNotFound is returned by NetworkInfo if we were unable to
+	// find the requested network.
+	ErrNetworkNotFound = errors.New("failed to locate the network")
+)
+
+// NetworkInput is used to provide inputs to the CreateNetwork and
+// ListNetworks functions.
+type NetworkInput struct {
+	// Name is the name of the network. A unique name is generated by
+	// CreateNetwork if one is not provided.
+	Name string
+
+	// Driver is the network driver, Docker uses bridge if not provided.
+	Driver string
+
+	// Internal restricts external access to the network.
+	Internal bool
+
+	// Labels are applied to the network.
+	Labels map[string]string
+}
+
+// NewNetworkInput produces a *NetworkInput struct. The dockertest label
+// is applied so the network can be found and cleaned up later.
+func NewNetworkInput(name string) *NetworkInput {
+	return &NetworkInput{
+		Name:   name,
+		Labels: map[string]string{"dockertest": "1"},
+	}
+}
+
+// NetworkCreate converts *NetworkInput into the options which may be
+// passed to the NetworkCreate() API call.
+func (n *NetworkInput) NetworkCreate() types.NetworkCreate {
+	return types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         n.Driver,
+		Internal:       n.Internal,
+		Labels:         n.Labels,
+	}
+}
+
+// FilterArgs converts *NetworkInput into a filters.Args struct which may
+// be used with the docker client directly.
+func (n *NetworkInput) FilterArgs() filters.Args {
+	args := filters.NewArgs()
+	if n.Name != "" {
+		args.Add("name", n.Name)
+	}
+	if n.Driver != "" {
+		args.Add("driver", n.Driver)
+	}
+	for key, value := range n.Labels {
+		args.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
+	return args
+}
+
+// NetworkInfo provides a wrapper around information about a network.
+type NetworkInfo struct {
+	JSON   types.NetworkResource
+	client *DockerClient
+}
+
+// ID is a shortcut function to return the network's id.
+func (n *NetworkInfo) ID() string {
+	return n.JSON.ID
+}
+
+// Name is a shortcut function to return the network's name.
+func (n *NetworkInfo) Name() string {
+	return n.JSON.Name
+}
+
+// Remove disconnects any containers from the network and removes it.
+func (n *NetworkInfo) Remove(ctx context.Context) error {
+	return n.client.RemoveNetwork(ctx, n.ID())
+}
+
+// randomName returns a name with the given prefix which is unlikely to
+// be in use.
+func randomName(prefix string) (string, error) {
+	data := make([]byte, 6)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(data), nil
+}
+
+// CreateNetwork creates a new network which containers may be connected
+// to using ClientInput.Networks. The network is labeled with the client's
+// Session.
+func (d *DockerClient) CreateNetwork(ctx context.Context, input *NetworkInput) (*NetworkInfo, error) {
+	name := input.Name
+	if name == "" {
+		generated, err := randomName("dockertest-")
+		if err != nil {
+			return nil, err
+		}
+		name = generated
+	}
+
+	options := input.NetworkCreate()
+	options.Labels = d.session.apply(options.Labels)
+	createctx, cancel := d.callContext(ctx)
+	created, err := d.docker.NetworkCreate(createctx, name, options)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	return d.NetworkInfo(ctx, created.ID)
+}
+
+// NetworkInfo retrieves a single network by id or name.
+func (d *DockerClient) NetworkInfo(ctx context.Context, id string) (*NetworkInfo, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	resource, err := d.docker.NetworkInspect(ctx, id, types.NetworkInspectOptions{})
+	if client.IsErrNotFound(err) {
+		return nil, ErrNetworkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &NetworkInfo{JSON: resource, client: d}, nil
+}
+
+// ListNetworks returns the networks matching the provided input.
+func (d *DockerClient) ListNetworks(ctx context.Context, input *NetworkInput) ([]*NetworkInfo, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	resources, err := d.docker.NetworkList(ctx, types.NetworkListOptions{Filters: input.FilterArgs()})
+	if err != nil {
+		return nil, err
+	}
+	networks := []*NetworkInfo{}
+	for _, resource := range resources {
+		networks = append(networks, &NetworkInfo{JSON: resource, client: d})
+	}
+	return networks, nil
+}
+
+// RemoveNetwork will delete the requested network, disconnecting any
+// containers which are still attached to it. Removing a network which
+// does not exist is not an error.
+func (d *DockerClient) RemoveNetwork(ctx context.Context, id string) error {
+	info, err := d.NetworkInfo(ctx, id)
+	if err == ErrNetworkNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for container := range info.JSON.Containers {
+		disconnectctx, cancel := d.callContext(ctx)
+		err := d.docker.NetworkDisconnect(disconnectctx, info.ID(), container, true)
+		cancel()
+		if err != nil && !strings.Contains(err.Error(), "not connected") && !strings.Contains(err.Error(), "No such container") {
+			return err
+		}
+	}
+
+	removectx, cancel := d.callContext(ctx)
+	defer cancel()
+	err = d.docker.NetworkRemove(removectx, info.ID())
+	if client.IsErrNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package dockertest
+
+import (
+	"context"
+
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type NetworkTest struct{}
+
+var _ = Suite(&NetworkTest{})
+
+func (s *NetworkTest) TestNewNetworkInput(c *C) {
+	input := NewNetworkInput("testing")
+	c.Assert(input, DeepEquals, &NetworkInput{
+		Name:   "testing",
+		Labels: map[string]string{"dockertest": "1"},
+	})
+	create := input.NetworkCreate()
+	c.Assert(create.CheckDuplicate, Equals, true)
+	c.Assert(create.Labels, DeepEquals, input.Labels)
+}
+
+func (s *NetworkTest) TestCreateNetwork(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+
+	created, err := dc.CreateNetwork(context.Background(), NewNetworkInput("testing"))
+	c.Assert(err, IsNil)
+	c.Assert(created.Name(), Equals, "testing")
+	c.Assert(created.JSON.Labels["dockertest"], Equals, "1")
+	c.Assert(created.JSON.Labels[SessionLabel], Equals, dc.Session().ID)
+
+	_, err = dc.CreateNetwork(context.Background(), NewNetworkInput("testing"))
+	c.Assert(err, ErrorMatches, ".*already exists.*")
+
+	generated, err := dc.CreateNetwork(context.Background(), NewNetworkInput(""))
+	c.Assert(err, IsNil)
+	c.Assert(generated.Name(), Matches, "dockertest-[0-9a-f]{12}")
+
+	networks, err := dc.ListNetworks(context.Background(), NewNetworkInput(""))
+	c.Assert(err, IsNil)
+	c.Assert(networks, HasLen, 2)
+
+	info, err := dc.NetworkInfo(context.Background(), "testing")
+	c.Assert(err, IsNil)
+	c.Assert(info.ID(), Equals, created.ID())
+	_, err = dc.NetworkInfo(context.Background(), "missing")
+	c.Assert(err, Equals, ErrNetworkNotFound)
+}
+
+func (s *NetworkTest) TestRemoveNetwork(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	created, err := dc.CreateNetwork(context.Background(), NewNetworkInput("testing"))
+	c.Assert(err, IsNil)
+	input := NewClientInput(testImage)
+	input.Networks = []string{"testing"}
+	_, err = dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	// Running containers are disconnected before removal.
+	c.Assert(created.Remove(context.Background()), IsNil)
+	_, err = dc.NetworkInfo(context.Background(), created.ID())
+	c.Assert(err, Equals, ErrNetworkNotFound)
+
+	// Removing a network which does not exist is not an error.
+	c.Assert(dc.RemoveNetwork(context.Background(), created.ID()), IsNil)
+}
+
+func (s *NetworkTest) TestRunContainerNetworks(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	for _, name := range []string{"first", "second"} {
+		_, err := dc.CreateNetwork(context.Background(), NewNetworkInput(name))
+		c.Assert(err, IsNil)
+	}
+
+	input := NewClientInput(testImage)
+	input.Networks = []string{"first", "second"}
+	input.Aliases = []string{"db"}
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	addresses := info.IPAddresses()
+	c.Assert(addresses, HasLen, 2)
+	c.Assert(addresses["first"], Not(Equals), "")
+	c.Assert(addresses["second"], Not(Equals), "")
+	address, err := info.IPAddress("second")
+	c.Assert(err, IsNil)
+	c.Assert(address, Equals, addresses["second"])
+	_, err = info.IPAddress("bridge")
+	c.Assert(err, Equals, ErrNetworkNotConnected)
+
+	for _, name := range input.Networks {
+		settings := info.JSON.NetworkSettings.Networks[name]
+		c.Assert(settings.Aliases, DeepEquals, []string{"db", info.ID()[:12]})
+	}
+	c.Assert(string(info.JSON.HostConfig.NetworkMode), Equals, "first")
+}
+
+func (s *NetworkTest) TestRunContainerNetworkNotFound(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	input := NewClientInput(testImage)
+	input.Networks = []string{"missing"}
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, ErrorMatches, ".*network missing not found.*")
+}
@@ -0,0 +1,122 @@
+package dockertest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+// PullPolicy controls when RunContainer will pull an image.
+type PullPolicy string
+
+const (
+	// PullIfNotPresent will only pull the image if it does not exist
+	// locally. This is the default.
+	PullIfNotPresent PullPolicy = "if-not-present"
+
+	// PullAlways will always pull the image before creating the
+	// container.
+	PullAlways PullPolicy = "always"
+
+	// PullNever will never pull the image. Creating the container
+	// will fail if the image does not exist locally.
+	PullNever PullPolicy = "never"
+)
+
+var (
+	// ErrInvalidPullPolicy is returned by RunContainer if the provided
+	// PullPolicy is not known.
+	ErrInvalidPullPolicy = errors.New("invalid pull policy")
+)
+
+// StreamError is an error reported by Docker inside of a progress
+// stream rather than by the response status.
+type StreamError struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *StreamError) Error() string {
+	return e.Message
+}
+
+// ProgressMessage is a single message decoded from the progress stream
+// Docker returns while pulling or building images.
+type ProgressMessage struct {
+	Stream       string           `json:"stream,omitempty"`
+	Status       string           `json:"status,omitempty"`
+	Progress     string           `json:"progress,omitempty"`
+	ID           string           `json:"id,omitempty"`
+	Error        *StreamError     `json:"errorDetail,omitempty"`
+	ErrorMessage string           `json:"error,omitempty"`
+	Aux          *json.RawMessage `json:"aux,omitempty"`
+}
+
+// Progress is a function which receives progress messages while an image
+// is being pulled.
+type Progress func(*ProgressMessage)
+
+// PullError is returned when Docker reports an error while pulling an
+// image, such as an unknown manifest or a rate limit.
+type PullError struct {
+	Image   string
+	Code    int
+	Message string
+}
+
+func (e *PullError) Error() string {
+	return fmt.Sprintf("failed to pull %s: %s", e.Image, e.Message)
+}
+
+// readProgress decodes a progress stream, passing each message to
+// progress if provided. The first error reported inside of the stream
+// is returned.
+func readProgress(reader io.Reader, progress Progress) error {
+	decoder := json.NewDecoder(reader)
+	for {
+		message := &ProgressMessage{}
+		if err := decoder.Decode(message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if progress != nil {
+			progress(message)
+		}
+		if message.Error != nil {
+			return message.Error
+		}
+		if message.ErrorMessage != "" {
+			return &StreamError{Message: message.ErrorMessage}
+		}
+	}
+}
+
+// pull retrieves the requested image, decoding the progress stream so
+// errors reported by Docker are not lost. If auth is nil credentials are
+// resolved using RegistryAuth.
+func (d *DockerClient) pull(ctx context.Context, image string, auth *types.AuthConfig, progress Progress) error {
+	encoded, err := d.encodedRegistryAuth(ctx, image, auth)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	reader, err := d.docker.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: encoded})
+	if err != nil {
+		return err
+	}
+	defer reader.Close() // nolint: errcheck
+
+	err = readProgress(reader, progress)
+	if streamErr, ok := err.(*StreamError); ok {
+		return &PullError{Image: image, Code: streamErr.Code, Message: streamErr.Message}
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package dockertest
+
+import (
+	"context"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type PullTest struct{}
+
+var _ = Suite(&PullTest{})
+
+func (s *PullTest) TestReadProgress(c *C) {
+	messages := []*ProgressMessage{}
+	stream := `{"status":"Pulling"}` + "\n" + `{"status":"Done","id":"abc"}`
+	err := readProgress(strings.NewReader(stream), func(message *ProgressMessage) {
+		messages = append(messages, message)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(messages, DeepEquals, []*ProgressMessage{{Status: "Pulling"}, {Status: "Done", ID: "abc"}})
+}
+
+func (s *PullTest) TestReadProgressStreamError(c *C) {
+	stream := `{"status":"Pulling"}{"errorDetail":{"code":429,"message":"rate limited"},"error":"rate limited"}{"status":"ignored"}`
+	err := readProgress(strings.NewReader(stream), nil)
+	c.Assert(err, DeepEquals, &StreamError{Code: 429, Message: "rate limited"})
+
+	err = readProgress(strings.NewReader(`{"error":"legacy"}`), nil)
+	c.Assert(err, DeepEquals, &StreamError{Message: "legacy"})
+}
+
+func (s *PullTest) TestReadProgressInvalid(c *C) {
+	c.Assert(readProgress(strings.NewReader(`{`), nil), NotNil)
+}
+
+func (s *PullTest) TestPullError(c *C) {
+	err := &PullError{Image: "test", Message: "manifest unknown"}
+	c.Assert(err.Error(), Equals, "failed to pull test: manifest unknown")
+}
+
+func (s *PullTest) TestPullIfNotPresent(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	server.AddRemoteImage("remote", &fakeengine.Image{})
+
+	pulled := 0
+	input := NewClientInput(testImage)
+	input.PullProgress = func(*ProgressMessage) { pulled++ }
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(pulled, Equals, 0)
+
+	input.Image = "remote"
+	input.PullPolicy = PullIfNotPresent
+	_, err = dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(pulled > 0, Equals, true)
+}
+
+func (s *PullTest) TestPullAlways(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	server.AddRemoteImage(testImage, &fakeengine.Image{})
+
+	statuses := []string{}
+	input := NewClientInput(testImage)
+	input.PullPolicy = PullAlways
+	input.PullProgress = func(message *ProgressMessage) {
+		statuses = append(statuses, message.Status)
+	}
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(statuses[len(statuses)-1], Equals, "Status: Downloaded newer image for "+testImage)
+}
+
+func (s *PullTest) TestPullNever(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddRemoteImage(testImage, &fakeengine.Image{})
+
+	input := NewClientInput(testImage)
+	input.PullPolicy = PullNever
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(client.IsErrNotFound(err), Equals, true)
+	c.Assert(server.HasImage(testImage), Equals, false)
+}
+
+func (s *PullTest) TestPullStreamError(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddRemoteImage(testImage, &fakeengine.Image{PullError: "manifest unknown"})
+
+	_, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, DeepEquals, &PullError{Image: testImage, Message: "manifest unknown"})
+}
+
+func (s *PullTest) TestInvalidPullPolicy(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+
+	input := NewClientInput(testImage)
+	input.PullPolicy = "sometimes"
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, Equals, ErrInvalidPullPolicy)
+}
@@ -0,0 +1,253 @@
+package dockertest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// ReaperLabel is the label used to record the session a reaper
+	// container belongs to. Reapers do not carry SessionLabel, so they
+	// are not removed by the cleanup they perform, and containers with
+	// ReaperLabel are never removed by DockerClient.Cleanup.
+	ReaperLabel = "dockertest.reaper"
+
+	// reaperImage is the tag given to the reaper image so it is only
+	// built once and then reused. The version must be increased when
+	// the reaper program changes.
+	reaperImage = "dockertest-reaper:1"
+
+	// reaperPackage is the package containing the reaper program.
+	reaperPackage = "github.com/opalmer/dockertest/reaper"
+
+	// reaperPort is the port the reaper listens for connections on.
+	reaperPort = 8080
+
+	// reaperSocket is the path the Docker socket is mounted at inside
+	// of the reaper when the daemon is reached using a unix socket.
+	reaperSocket = "/var/run/docker.sock"
+
+	// reaperBuildTimeout limits how long building the reaper image may
+	// take, which includes compiling the module without a build cache.
+	reaperBuildTimeout = time.Minute * 5
+
+	// reaperTimeout limits how long StartReaper waits for the reaper
+	// to accept a connection.
+	reaperTimeout = time.Second * 30
+
+	reaperDockerfile = `FROM scratch
+COPY reaper /reaper
+EXPOSE 8080
+ENTRYPOINT ["/reaper"]
+`
+)
+
+var (
+	// ErrReaperNotReady is returned by StartReaper if the reaper did not
+	// acknowledge the connection to it.
+	ErrReaperNotReady = errors.New("reaper did not acknowledge the connection")
+
+	// ErrReaperUnsupportedHost is returned by StartReaper if the reaper
+	// would not be able to reach the Docker daemon from inside of its
+	// container. Daemons using TLS or listening on a loopback address
+	// are not supported.
+	ErrReaperUnsupportedHost = errors.New("the reaper cannot reach the Docker daemon")
+)
+
+// buildReaper compiles the reaper program into dir. The program is built
+// for linux on the current architecture using the local Go toolchain so
+// no images need to be downloaded.
+var buildReaper = func(ctx context.Context, dir string) error {
+	command := exec.CommandContext(
+		ctx, "go", "build", "-o", filepath.Join(dir, "reaper"), reaperPackage)
+	command.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS=linux", "GOARCH="+runtime.GOARCH)
+	output, err := command.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to build the reaper: %w: %s", err, output)
+	}
+	return nil
+}
+
+// dialReaper opens a connection to the reaper.
+var dialReaper = func(ctx context.Context, address string) (net.Conn, error) {
+	return (&net.Dialer{}).DialContext(ctx, "tcp", address)
+}
+
+// StartReaper starts a reaper container for the session and connects to
+// it. The reaper removes every resource created in the session once the
+// connection is closed which happens when StopReaper is called or the
+// process exits for any reason, including a panic, a test timeout or
+// os.Exit where signal handlers and deferred calls do not run:
+//
+//	func TestMain(m *testing.M) {
+//	    session := client.Session()
+//	    if err := session.StartReaper(context.Background()); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    os.Exit(session.Run(m))
+//	}
+//
+// The reaper image is built from the reaper package using the local Go
+// toolchain, so Go must be installed, the first time it is needed and
+// then reused. Calling StartReaper again once the reaper is running does
+// nothing.
+func (s *Session) StartReaper(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reaper != nil {
+		return nil
+	}
+
+	binds, host, err := reaperDaemon(s.client.host, s.client.tls)
+	if err != nil {
+		return err
+	}
+	input := s.reaperInput(binds, host)
+	info, err := s.client.RunContainer(ctx, input)
+	if client.IsErrNotFound(err) {
+		if err := s.buildReaperImage(ctx); err != nil {
+			return err
+		}
+		info, err = s.client.RunContainer(ctx, input)
+	}
+	if err != nil {
+		return err
+	}
+
+	connectctx, cancel := context.WithTimeout(ctx, reaperTimeout)
+	defer cancel()
+	conn, err := s.connectReaper(connectctx, info)
+	if err != nil {
+		cleanupctx, cancel := cleanupContext(ctx)
+		defer cancel()
+		s.client.RemoveContainer(cleanupctx, info.ID()) // nolint: errcheck
+		return err
+	}
+	s.reaper = conn
+	return nil
+}
+
+// StopReaper closes the connection to the reaper which then removes every
+// resource created in the session and exits.
+func (s *Session) StopReaper() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reaper == nil {
+		return nil
+	}
+	err := s.reaper.Close()
+	s.reaper = nil
+	return err
+}
+
+// reaperDaemon returns the binds and DOCKER_HOST the reaper needs to
+// reach the daemon at host.
+func reaperDaemon(host string, tls bool) ([]string, string, error) {
+	if tls {
+		return nil, "", fmt.Errorf("%w: %s uses TLS", ErrReaperUnsupportedHost, host)
+	}
+	parsed, err := url.Parse(host)
+	if err != nil {
+		return nil, "", err
+	}
+	switch parsed.Scheme {
+	case "unix":
+		return []string{parsed.Path + ":" + reaperSocket}, "unix://" + reaperSocket, nil
+	case "tcp":
+		// A loopback address inside of the container is the container
+		// itself rather than the machine running the daemon.
+		hostname := parsed.Hostname()
+		if ip := net.ParseIP(hostname); hostname == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil, "", fmt.Errorf("%w: %s is a loopback address", ErrReaperUnsupportedHost, host)
+		}
+		return nil, host, nil
+	default:
+		return nil, "", fmt.Errorf("%w: %q", ErrReaperUnsupportedHost, host)
+	}
+}
+
+// buildReaperImage compiles the reaper and builds reaperImage from it.
+func (s *Session) buildReaperImage(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, reaperBuildTimeout)
+	defer cancel()
+	dir, err := ioutil.TempDir("", "dockertest-reaper-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+	if err := buildReaper(ctx, dir); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(reaperDockerfile), 0644); err != nil {
+		return err
+	}
+
+	input := NewBuildInput(dir)
+	input.Tags = []string{reaperImage}
+	_, err = s.client.BuildImage(ctx, input)
+	return err
+}
+
+// reaperInput returns the input used to run the reaper. The reaper image
+// is never pulled, StartReaper builds it if it does not exist.
+func (s *Session) reaperInput(binds []string, host string) *ClientInput {
+	input := NewClientInput(reaperImage)
+	input.PullPolicy = PullNever
+	input.SetLabel(ReaperLabel, s.ID)
+	input.AddEnvironmentVar("DOCKER_HOST", host)
+	input.Command = []string{"-session", s.ID, "-listen", ":" + strconv.Itoa(reaperPort)}
+	input.Ports.Add(&Port{Private: reaperPort, Public: RandomPort, Protocol: ProtocolTCP})
+	input.ModifyConfig = func(config *container.Config, hostConfig *container.HostConfig) {
+		// RunContainer labels everything with the session but the
+		// reaper must survive the cleanup it performs.
+		for key := range s.Labels() {
+			delete(config.Labels, key)
+		}
+		hostConfig.Binds = append(hostConfig.Binds, binds...)
+		hostConfig.AutoRemove = true
+	}
+	return input
+}
+
+// connectReaper connects to the reaper, retrying until the reaper has
+// started listening and acknowledges the connection.
+func (s *Session) connectReaper(ctx context.Context, info *ContainerInfo) (net.Conn, error) {
+	port, err := info.Port(reaperPort)
+	if err != nil {
+		return nil, err
+	}
+	address := net.JoinHostPort(port.Address, strconv.Itoa(int(port.Public)))
+	for {
+		conn, err := dialReaper(ctx, address)
+		if err == nil {
+			if deadline, ok := ctx.Deadline(); ok {
+				conn.SetReadDeadline(deadline) // nolint: errcheck
+			}
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err == nil && line == "ACK\n" {
+				conn.SetReadDeadline(time.Time{}) // nolint: errcheck
+				return conn, nil
+			}
+			conn.Close() // nolint: errcheck
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ErrReaperNotReady
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// The reaper removes the containers, networks and volumes created in a
+// dockertest session once the process running the tests goes away. It
+// is run as a container by Session.StartReaper, the test process holds a
+// connection to it and when every connection has been closed, including
+// because the process crashed or was killed, the reaper calls Cleanup for
+// the session and exits.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/opalmer/dockertest"
+)
+
+// ack is written to each connection once the reaper is watching it.
+const ack = "ACK\n"
+
+// reaper waits for the connections from a test process to close.
+type reaper struct {
+	// connectTimeout is how long to wait for the first connection
+	// before giving up on the test process.
+	connectTimeout time.Duration
+
+	mu     sync.Mutex
+	active int
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newReaper(connectTimeout time.Duration) *reaper {
+	return &reaper{connectTimeout: connectTimeout, done: make(chan struct{})}
+}
+
+func (r *reaper) finish() {
+	r.once.Do(func() { close(r.done) })
+}
+
+// watch acknowledges the connection then waits for it to close.
+func (r *reaper) watch(conn net.Conn) {
+	defer conn.Close() // nolint: errcheck
+	if _, err := io.WriteString(conn, ack); err == nil {
+		io.Copy(ioutil.Discard, bufio.NewReader(conn)) // nolint: errcheck
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active--
+	if r.active == 0 {
+		r.finish()
+	}
+}
+
+// serve accepts connections until every connection has been closed or
+// no connection was made within connectTimeout.
+func (r *reaper) serve(listener net.Listener) {
+	timer := time.AfterFunc(r.connectTimeout, r.finish)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				r.finish()
+				return
+			}
+			timer.Stop()
+			r.mu.Lock()
+			r.active++
+			r.mu.Unlock()
+			go r.watch(conn)
+		}
+	}()
+	<-r.done
+	listener.Close() // nolint: errcheck
+}
+
+// reap removes everything created in the session.
+func reap(ctx context.Context, docker dockertest.Engine, session string) (*dockertest.CleanupReport, error) {
+	return dockertest.NewClientWithEngine(docker).Cleanup(ctx, &dockertest.CleanupPolicy{Session: session})
+}
+
+func main() {
+	session := flag.String("session", "", "The id of the session to clean up.")
+	listen := flag.String("listen", ":8080", "The address to listen for connections on.")
+	connectTimeout := flag.Duration(
+		"connect-timeout", time.Minute, "How long to wait for the first connection.")
+	timeout := flag.Duration("timeout", time.Minute*5, "How long to wait for cleanup to finish.")
+	flag.Parse()
+	if *session == "" {
+		log.Fatal("-session is required")
+	}
+
+	docker, err := client.NewEnvClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	newReaper(*connectTimeout).serve(listener)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	report, err := reap(ctx, docker, *session)
+	if report != nil {
+		log.Printf("removed containers=%v networks=%v volumes=%v",
+			report.Containers, report.Networks, report.Volumes)
+	}
+	if err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/opalmer/dockertest"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type ReaperTest struct{}
+
+var _ = Suite(&ReaperTest{})
+
+// start runs the reaper in the background, the returned channel is
+// closed once it stops serving.
+func start(c *C, connectTimeout time.Duration) (string, chan struct{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	stopped := make(chan struct{})
+	go func() {
+		newReaper(connectTimeout).serve(listener)
+		close(stopped)
+	}()
+	return listener.Addr().String(), stopped
+}
+
+func connect(c *C, address string) net.Conn {
+	conn, err := net.Dial("tcp", address)
+	c.Assert(err, IsNil)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	c.Assert(err, IsNil)
+	c.Assert(line, Equals, ack)
+	return conn
+}
+
+func stopped(stopped chan struct{}) bool {
+	select {
+	case <-stopped:
+		return true
+	case <-time.After(time.Millisecond * 100):
+		return false
+	}
+}
+
+func (s *ReaperTest) TestServeUntilConnectionsClosed(c *C) {
+	address, done := start(c, time.Minute)
+	first := connect(c, address)
+	second := connect(c, address)
+	c.Assert(first.Close(), IsNil)
+	c.Assert(stopped(done), Equals, false)
+	c.Assert(second.Close(), IsNil)
+	c.Assert(stopped(done), Equals, true)
+}
+
+func (s *ReaperTest) TestServeConnectTimeout(c *C) {
+	_, done := start(c, time.Millisecond)
+	c.Assert(stopped(done), Equals, true)
+}
+
+func (s *ReaperTest) TestReap(c *C) {
+	server, err := fakeengine.NewServer()
+	c.Assert(err, IsNil)
+	defer server.Close() // nolint: errcheck
+	server.AddImage("alpine", &fakeengine.Image{})
+
+	docker, err := server.Client()
+	c.Assert(err, IsNil)
+	dc := dockertest.NewClientWithEngine(docker)
+	info, err := dc.RunContainer(context.Background(), dockertest.NewClientInput("alpine"))
+	c.Assert(err, IsNil)
+	network, err := dc.CreateNetwork(context.Background(), dockertest.NewNetworkInput(""))
+	c.Assert(err, IsNil)
+
+	input := dockertest.NewClientInput("alpine")
+	input.ModifyConfig = func(config *container.Config, hostConfig *container.HostConfig) {
+		config.Labels[dockertest.SessionLabel] = "other"
+	}
+	kept, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	report, err := reap(context.Background(), docker, dc.Session().ID)
+	c.Assert(err, IsNil)
+	c.Assert(report.Containers, DeepEquals, []string{info.ID()})
+	c.Assert(report.Networks, DeepEquals, []string{network.ID()})
+	_, err = dc.ContainerInfo(context.Background(), kept.ID())
+	c.Assert(err, IsNil)
+}
@@ -0,0 +1,193 @@
+package dockertest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type ReaperTest struct {
+	build  func(context.Context, string) error
+	dial   func(context.Context, string) (net.Conn, error)
+	builds int
+}
+
+var _ = Suite(&ReaperTest{})
+
+func (s *ReaperTest) SetUpTest(c *C) {
+	s.build, s.dial = buildReaper, dialReaper
+	s.builds = 0
+	buildReaper = func(ctx context.Context, dir string) error {
+		s.builds++
+		return ioutil.WriteFile(filepath.Join(dir, "reaper"), []byte("reaper"), 0755)
+	}
+}
+
+func (s *ReaperTest) TearDownTest(c *C) {
+	buildReaper, dialReaper = s.build, s.dial
+}
+
+// fakeReaper acknowledges connections like the reaper does, closed
+// receives a value when the client closes its connection.
+func fakeReaper(closed chan struct{}) func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			io.WriteString(server, "ACK\n")                  // nolint: errcheck
+			io.Copy(ioutil.Discard, bufio.NewReader(server)) // nolint: errcheck
+			closed <- struct{}{}
+		}()
+		return client, nil
+	}
+}
+
+// newUnixFakeClient is newFakeClient using a unix socket, the reaper
+// cannot reach a daemon listening on a loopback address.
+func newUnixFakeClient(c *C) (*DockerClient, *fakeengine.Server, string) {
+	path := filepath.Join(c.MkDir(), "docker.sock")
+	server, err := fakeengine.NewUnixServer(path)
+	c.Assert(err, IsNil)
+	docker, err := server.Client()
+	c.Assert(err, IsNil)
+	return NewClientWithEngine(docker), server, path
+}
+
+func (s *ReaperTest) TestReaperDaemon(c *C) {
+	binds, host, err := reaperDaemon("unix:///run/user/1000/docker.sock", false)
+	c.Assert(err, IsNil)
+	c.Assert(binds, DeepEquals, []string{"/run/user/1000/docker.sock:" + reaperSocket})
+	c.Assert(host, Equals, "unix://"+reaperSocket)
+
+	binds, host, err = reaperDaemon("tcp://192.168.99.100:2375", false)
+	c.Assert(err, IsNil)
+	c.Assert(binds, HasLen, 0)
+	c.Assert(host, Equals, "tcp://192.168.99.100:2375")
+
+	for _, host := range []string{"tcp://127.0.0.1:2375", "tcp://localhost:2375", "tcp://[::1]:2375", "npipe:////./pipe/docker_engine", ""} {
+		_, _, err = reaperDaemon(host, false)
+		c.Assert(errors.Is(err, ErrReaperUnsupportedHost), Equals, true, Commentf(host))
+	}
+	_, _, err = reaperDaemon("tcp://192.168.99.100:2376", true)
+	c.Assert(errors.Is(err, ErrReaperUnsupportedHost), Equals, true)
+}
+
+func (s *ReaperTest) TestStartReaper(c *C) {
+	dc, server, path := newUnixFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	closed := make(chan struct{}, 1)
+	dialReaper = fakeReaper(closed)
+	session := dc.Session()
+
+	c.Assert(session.StartReaper(context.Background()), IsNil)
+	c.Assert(session.StartReaper(context.Background()), IsNil)
+
+	input := &ClientInput{Labels: map[string]string{ReaperLabel: session.ID}, All: true}
+	reapers, err := dc.ListContainers(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(reapers, HasLen, 1)
+	reaper := reapers[0]
+	c.Assert([]string(reaper.JSON.Config.Cmd), DeepEquals, []string{"-session", session.ID, "-listen", ":8080"})
+	c.Assert(reaper.JSON.HostConfig.Binds, DeepEquals, []string{path + ":" + reaperSocket})
+	c.Assert(reaper.JSON.Config.Env, DeepEquals, []string{"DOCKER_HOST=unix://" + reaperSocket})
+	c.Assert(reaper.JSON.HostConfig.AutoRemove, Equals, true)
+	_, err = reaper.Port(reaperPort)
+	c.Assert(err, IsNil)
+
+	// The reaper is not removed by the cleanup it performs.
+	for _, label := range []string{SessionLabel, HostnameLabel, PIDLabel} {
+		_, ok := reaper.GetLabel(label)
+		c.Assert(ok, Equals, false, Commentf(label))
+	}
+	report, err := session.Cleanup(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(report.Containers, DeepEquals, []string{})
+
+	c.Assert(session.StopReaper(), IsNil)
+	<-closed
+	c.Assert(session.StopReaper(), IsNil)
+
+	// The image is reused by later reapers.
+	c.Assert(session.StartReaper(context.Background()), IsNil)
+	c.Assert(s.builds, Equals, 1)
+	c.Assert(session.StopReaper(), IsNil)
+	<-closed
+}
+
+func (s *ReaperTest) TestCleanupKeepsReaper(c *C) {
+	dc, server, _ := newUnixFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	closed := make(chan struct{}, 1)
+	dialReaper = fakeReaper(closed)
+	session := dc.Session()
+	c.Assert(session.StartReaper(context.Background()), IsNil)
+
+	// Other processes cleaning up with the default policy must not
+	// remove the reaper of a session which is still running.
+	report, err := dc.Cleanup(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Containers, DeepEquals, []string{})
+	input := &ClientInput{Labels: map[string]string{ReaperLabel: session.ID}, All: true}
+	reapers, err := dc.ListContainers(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(reapers, HasLen, 1)
+
+	c.Assert(session.StopReaper(), IsNil)
+	<-closed
+}
+
+func (s *ReaperTest) TestStartReaperUnsupportedHost(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	err := dc.Session().StartReaper(context.Background())
+	c.Assert(errors.Is(err, ErrReaperUnsupportedHost), Equals, true)
+	c.Assert(s.builds, Equals, 0)
+}
+
+func (s *ReaperTest) TestStartReaperBuildError(c *C) {
+	dc, server, _ := newUnixFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	buildReaper = func(ctx context.Context, dir string) error {
+		return errors.New("go: not found")
+	}
+	c.Assert(dc.Session().StartReaper(context.Background()), ErrorMatches, "go: not found")
+}
+
+func (s *ReaperTest) TestStartReaperBuildDeadline(c *C) {
+	dc, server, _ := newUnixFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	dialReaper = fakeReaper(make(chan struct{}, 1))
+	build := buildReaper
+	buildReaper = func(ctx context.Context, dir string) error {
+		deadline, ok := ctx.Deadline()
+		c.Assert(ok, Equals, true)
+		c.Assert(time.Until(deadline) > reaperTimeout, Equals, true)
+		return build(ctx, dir)
+	}
+	c.Assert(dc.Session().StartReaper(context.Background()), IsNil)
+	c.Assert(dc.Session().StopReaper(), IsNil)
+}
+
+func (s *ReaperTest) TestStartReaperNotReady(c *C) {
+	dc, server, _ := newUnixFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	dialReaper = func(ctx context.Context, address string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer cancel()
+	c.Assert(dc.Session().StartReaper(ctx), Equals, ErrReaperNotReady)
+
+	// The reaper container is removed if the connection fails.
+	input := &ClientInput{Labels: map[string]string{ReaperLabel: dc.Session().ID}, All: true}
+	reapers, err := dc.ListContainers(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(reapers, HasLen, 0)
+}
@@ -0,0 +1,171 @@
+package dockertest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+)
+
+// minimumMemory is the smallest memory limit Docker accepts.
+const minimumMemory = 4 * 1024 * 1024
+
+var (
+	// ErrInvalidResources is returned by RunContainer if a resource
+	// limit on ClientInput is out of range.
+	ErrInvalidResources = errors.New("invalid resource limit")
+
+	// ErrInvalidCapability is returned by RunContainer if CapAdd or
+	// CapDrop contains an unknown capability.
+	ErrInvalidCapability = errors.New("unknown capability")
+
+	// ErrInvalidSysctl is returned by RunContainer if Sysctls contains
+	// a sysctl which may not be set for a container.
+	ErrInvalidSysctl = errors.New("sysctl may not be set for a container")
+
+	// ErrInvalidSecurityOpt is returned by RunContainer if SecurityOpt
+	// contains an unknown or malformed option.
+	ErrInvalidSecurityOpt = errors.New("invalid security option")
+
+	// ErrUlimitNameNotProvided is returned by RunContainer if one of
+	// Ulimits does not have a Name.
+	ErrUlimitNameNotProvided = errors.New("ulimit name not provided")
+)
+
+// capabilities are the Linux capabilities which may be added to or
+// dropped from a container, in addition to ALL.
+var capabilities = map[string]bool{
+	"ALL": true, "AUDIT_CONTROL": true, "AUDIT_READ": true, "AUDIT_WRITE": true,
+	"BLOCK_SUSPEND": true, "CHOWN": true, "DAC_OVERRIDE": true, "DAC_READ_SEARCH": true,
+	"FOWNER": true, "FSETID": true, "IPC_LOCK": true, "IPC_OWNER": true, "KILL": true,
+	"LEASE": true, "LINUX_IMMUTABLE": true, "MAC_ADMIN": true, "MAC_OVERRIDE": true,
+	"MKNOD": true, "NET_ADMIN": true, "NET_BIND_SERVICE": true, "NET_BROADCAST": true,
+	"NET_RAW": true, "SETFCAP": true, "SETGID": true, "SETPCAP": true, "SETUID": true,
+	"SYS_ADMIN": true, "SYS_BOOT": true, "SYS_CHROOT": true, "SYS_MODULE": true,
+	"SYS_NICE": true, "SYS_PACCT": true, "SYS_PTRACE": true, "SYS_RAWIO": true,
+	"SYS_RESOURCE": true, "SYS_TIME": true, "SYS_TTY_CONFIG": true, "SYSLOG": true,
+	"WAKE_ALARM": true,
+}
+
+// namespacedSysctls are the sysctls, other than those under the
+// prefixes in namespacedSysctlPrefixes, which Docker allows to be set
+// per container.
+var namespacedSysctls = map[string]bool{
+	"kernel.msgmax": true, "kernel.msgmnb": true, "kernel.msgmni": true,
+	"kernel.sem": true, "kernel.shmall": true, "kernel.shmmax": true,
+	"kernel.shmmni": true, "kernel.shm_rmid_forced": true,
+}
+
+var namespacedSysctlPrefixes = []string{"fs.mqueue.", "net."}
+
+// securityOptKeys are the keys which may be used in SecurityOpt.
+var securityOptKeys = map[string]bool{
+	"apparmor": true, "label": true, "no-new-privileges": true, "seccomp": true,
+}
+
+// Ulimit sets the soft and hard limits of a resource, such as nofile,
+// inside of the container.
+type Ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// validateHostOptions checks the resource limits and security options
+// on ClientInput so mistakes are reported before a container is created.
+func (i *ClientInput) validateHostOptions() error {
+	if i.Memory < 0 || (i.Memory > 0 && i.Memory < minimumMemory) {
+		return fmt.Errorf("%w: memory must be at least %d bytes", ErrInvalidResources, minimumMemory)
+	}
+	if i.MemorySwap < -1 {
+		return fmt.Errorf("%w: memory swap must be -1 or greater", ErrInvalidResources)
+	}
+	if i.MemorySwap != 0 && i.Memory == 0 {
+		return fmt.Errorf("%w: memory must be set when memory swap is set", ErrInvalidResources)
+	}
+	if i.MemorySwap > 0 && i.MemorySwap < i.Memory {
+		return fmt.Errorf("%w: memory swap must not be less than memory", ErrInvalidResources)
+	}
+	if i.CPUShares < 0 {
+		return fmt.Errorf("%w: cpu shares must not be negative", ErrInvalidResources)
+	}
+	if i.CPUQuota != 0 && i.CPUQuota < 1000 {
+		return fmt.Errorf("%w: cpu quota must be at least 1000 microseconds", ErrInvalidResources)
+	}
+	if i.CPUPeriod != 0 && (i.CPUPeriod < 1000 || i.CPUPeriod > 1000000) {
+		return fmt.Errorf("%w: cpu period must be between 1000 and 1000000 microseconds", ErrInvalidResources)
+	}
+	if i.PidsLimit < -1 {
+		return fmt.Errorf("%w: pids limit must be -1 or greater", ErrInvalidResources)
+	}
+	for _, ulimit := range i.Ulimits {
+		if ulimit.Name == "" {
+			return ErrUlimitNameNotProvided
+		}
+		if ulimit.Soft > ulimit.Hard {
+			return fmt.Errorf("%w: ulimit %q soft limit must not exceed hard limit", ErrInvalidResources, ulimit.Name)
+		}
+	}
+
+	for _, capability := range append(append([]string{}, i.CapAdd...), i.CapDrop...) {
+		name := strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+		if !capabilities[name] {
+			return fmt.Errorf("%w: %s", ErrInvalidCapability, capability)
+		}
+	}
+
+	for key := range i.Sysctls {
+		if !namespacedSysctl(key) {
+			return fmt.Errorf("%w: %s", ErrInvalidSysctl, key)
+		}
+	}
+
+	for _, option := range i.SecurityOpt {
+		key := option
+		if index := strings.IndexAny(option, "=:"); index >= 0 {
+			key = option[:index]
+		} else if option != "no-new-privileges" {
+			return fmt.Errorf("%w: %s", ErrInvalidSecurityOpt, option)
+		}
+		if !securityOptKeys[key] {
+			return fmt.Errorf("%w: %s", ErrInvalidSecurityOpt, option)
+		}
+	}
+	return nil
+}
+
+func namespacedSysctl(key string) bool {
+	if namespacedSysctls[key] {
+		return true
+	}
+	for _, prefix := range namespacedSysctlPrefixes {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyHostOptions copies the resource limits and security options on
+// ClientInput to config.
+func (i *ClientInput) applyHostOptions(config *container.HostConfig) {
+	config.Memory = i.Memory
+	config.MemorySwap = i.MemorySwap
+	config.CPUShares = i.CPUShares
+	config.CPUQuota = i.CPUQuota
+	config.CPUPeriod = i.CPUPeriod
+	config.PidsLimit = i.PidsLimit
+	for _, ulimit := range i.Ulimits {
+		config.Ulimits = append(config.Ulimits, &units.Ulimit{
+			Name: ulimit.Name, Soft: ulimit.Soft, Hard: ulimit.Hard,
+		})
+	}
+	config.Sysctls = i.Sysctls
+	config.CapAdd = i.CapAdd
+	config.CapDrop = i.CapDrop
+	config.ReadonlyRootfs = i.ReadOnlyRootfs
+	config.Privileged = i.Privileged
+	config.SecurityOpt = i.SecurityOpt
+}
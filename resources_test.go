@@ -0,0 +1,120 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/go-units"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type ResourcesTest struct{}
+
+var _ = Suite(&ResourcesTest{})
+
+func (s *ResourcesTest) TestValidateHostOptions(c *C) {
+	for _, test := range []struct {
+		modify func(*ClientInput)
+		err    error
+	}{
+		{func(i *ClientInput) {}, nil},
+		{func(i *ClientInput) { i.Memory = 1024 }, ErrInvalidResources},
+		{func(i *ClientInput) { i.Memory = -1 }, ErrInvalidResources},
+		{func(i *ClientInput) { i.MemorySwap = 64 << 20 }, ErrInvalidResources},
+		{func(i *ClientInput) { i.Memory, i.MemorySwap = 64<<20, 32<<20 }, ErrInvalidResources},
+		{func(i *ClientInput) { i.Memory, i.MemorySwap = 64<<20, -1 }, nil},
+		{func(i *ClientInput) { i.Memory, i.MemorySwap = 64<<20, -2 }, ErrInvalidResources},
+		{func(i *ClientInput) { i.CPUShares = -1 }, ErrInvalidResources},
+		{func(i *ClientInput) { i.CPUQuota = 10 }, ErrInvalidResources},
+		{func(i *ClientInput) { i.CPUPeriod = 10 }, ErrInvalidResources},
+		{func(i *ClientInput) { i.CPUQuota, i.CPUPeriod = 50000, 100000 }, nil},
+		{func(i *ClientInput) { i.PidsLimit = -2 }, ErrInvalidResources},
+		{func(i *ClientInput) { i.Ulimits = []Ulimit{{Name: "nofile", Soft: 2, Hard: 1}} }, ErrInvalidResources},
+		{func(i *ClientInput) { i.Ulimits = []Ulimit{{Soft: 1, Hard: 1}} }, ErrUlimitNameNotProvided},
+		{func(i *ClientInput) { i.CapAdd = []string{"net_admin", "CAP_SYS_PTRACE"} }, nil},
+		{func(i *ClientInput) { i.CapDrop = []string{"ALL"} }, nil},
+		{func(i *ClientInput) { i.CapDrop = []string{"FLY"} }, ErrInvalidCapability},
+		{func(i *ClientInput) { i.Sysctls = map[string]string{"net.core.somaxconn": "1024"} }, nil},
+		{func(i *ClientInput) { i.Sysctls = map[string]string{"kernel.shmmax": "1"} }, nil},
+		{func(i *ClientInput) { i.Sysctls = map[string]string{"vm.swappiness": "1"} }, ErrInvalidSysctl},
+		{func(i *ClientInput) {
+			i.SecurityOpt = []string{"no-new-privileges", "seccomp=unconfined", "label:disable"}
+		}, nil},
+		{func(i *ClientInput) { i.SecurityOpt = []string{"unconfined"} }, ErrInvalidSecurityOpt},
+		{func(i *ClientInput) { i.SecurityOpt = []string{"selinux=disable"} }, ErrInvalidSecurityOpt},
+	} {
+		input := NewClientInput("test")
+		test.modify(input)
+		err := input.validateHostOptions()
+		if test.err == nil {
+			c.Assert(err, IsNil)
+			continue
+		}
+		c.Assert(errors.Is(err, test.err), Equals, true, Commentf("%v", err))
+	}
+}
+
+func (s *ResourcesTest) TestHostConfig(c *C) {
+	input := NewClientInput("test")
+	input.Memory = 64 << 20
+	input.MemorySwap = 128 << 20
+	input.CPUShares = 512
+	input.CPUQuota = 50000
+	input.CPUPeriod = 100000
+	input.PidsLimit = 100
+	input.Ulimits = []Ulimit{{Name: "nofile", Soft: 1024, Hard: 2048}}
+	input.Sysctls = map[string]string{"net.core.somaxconn": "1024"}
+	input.CapAdd = []string{"NET_ADMIN"}
+	input.CapDrop = []string{"ALL"}
+	input.ReadOnlyRootfs = true
+	input.Privileged = true
+	input.SecurityOpt = []string{"no-new-privileges"}
+
+	config, err := input.HostConfig()
+	c.Assert(err, IsNil)
+	c.Assert(config.Memory, Equals, int64(64<<20))
+	c.Assert(config.MemorySwap, Equals, int64(128<<20))
+	c.Assert(config.CPUShares, Equals, int64(512))
+	c.Assert(config.CPUQuota, Equals, int64(50000))
+	c.Assert(config.CPUPeriod, Equals, int64(100000))
+	c.Assert(config.PidsLimit, Equals, int64(100))
+	c.Assert(config.Ulimits, DeepEquals, []*units.Ulimit{{Name: "nofile", Soft: 1024, Hard: 2048}})
+	c.Assert(config.Sysctls, DeepEquals, input.Sysctls)
+	c.Assert(config.CapAdd, DeepEquals, strslice.StrSlice{"NET_ADMIN"})
+	c.Assert(config.CapDrop, DeepEquals, strslice.StrSlice{"ALL"})
+	c.Assert(config.ReadonlyRootfs, Equals, true)
+	c.Assert(config.Privileged, Equals, true)
+	c.Assert(config.SecurityOpt, DeepEquals, []string{"no-new-privileges"})
+}
+
+func (s *ResourcesTest) TestRunContainerValidatesBeforeCreate(c *C) {
+	engine := &stubEngine{}
+	dc := NewClientWithEngine(engine)
+	input := NewClientInput("test")
+	input.CapAdd = []string{"FLY"}
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(errors.Is(err, ErrInvalidCapability), Equals, true)
+	c.Assert(engine.calls, HasLen, 0)
+}
+
+func (s *ResourcesTest) TestRunContainerReadOnlyRootfs(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	input := NewClientInput(testImage)
+	input.Memory = 64 << 20
+	input.ReadOnlyRootfs = true
+	input.Mounts = []Mount{TmpfsMount("/scratch", 0)}
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(info.JSON.HostConfig.Memory, Equals, int64(64<<20))
+	c.Assert(info.JSON.HostConfig.ReadonlyRootfs, Equals, true)
+
+	// Mounts remain writable while the rest of the filesystem is not.
+	files := FileMap{"data": []byte("data")}
+	c.Assert(info.CopyTo(context.Background(), "/scratch", files), IsNil)
+	c.Assert(info.CopyTo(context.Background(), "/tmp", files), ErrorMatches, ".*rootfs is marked read-only.*")
+}
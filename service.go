@@ -3,6 +3,11 @@ package dockertest
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"time"
 
 	"github.com/crewjam/errset"
 )
@@ -15,12 +20,24 @@ var (
 	// ErrContainerNotStarted is returned by Terminate() if the container
 	// was never started.
 	ErrContainerNotStarted = errors.New("container not started")
+
+	// ErrContainerExited is wrapped by the *StartupError returned if
+	// a container exits while it is starting up.
+	ErrContainerExited = errors.New("container exited")
 )
 
 // PingInput is used to provide inputs to a Ping function.
 type PingInput struct {
+	// Context is the context provided to Service.RunContext, bounded by
+	// the Service's RetryPolicy. It is cancelled if the Container exits.
+	// Ping functions should stop waiting once it is done.
+	Context   context.Context
 	Service   *Service
 	Container *ContainerInfo
+
+	// Attempt is the number of times, starting at 1, Ping has been
+	// called for the Container.
+	Attempt int
 }
 
 // Ping is a function that's used to ping a service before returning from
@@ -28,6 +45,56 @@ type PingInput struct {
 // Container to be removed.
 type Ping func(*PingInput) error
 
+// RetryPolicy controls how Service.Run retries Ping. The delay after
+// each failed attempt starts at InitialBackoff and is multiplied by
+// Multiplier, up to MaxBackoff, after every attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times Ping is called. There
+	// is no limit if this is zero.
+	MaxAttempts int
+
+	// Timeout is how long to keep calling Ping for. There is no limit,
+	// other than the context provided to Service.RunContext, if this is
+	// zero.
+	Timeout time.Duration
+
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Jitter randomly increases or decreases each delay by up to this
+	// fraction of it, for example 0.2 for 20%.
+	Jitter float64
+}
+
+// NewRetryPolicy produces a *RetryPolicy which retries Ping for up to a
+// minute, starting with a 100ms delay between attempts.
+func NewRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		Timeout:        time.Minute,
+		InitialBackoff: time.Millisecond * 100,
+		MaxBackoff:     time.Second * 5,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// backoff returns the delay to wait after the provided attempt failed.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1) // nolint: gosec
+	}
+	return time.Duration(delay)
+}
+
 // Service is a struct used to run and manage a Container for a specific
 // service.
 type Service struct {
@@ -38,9 +105,15 @@ type Service struct {
 	// Ping is a function that may be used to wait for the service
 	// to come up before returning. If this function is specified
 	// and it return an error Terminate() will be automatically
-	// called. This function is called by Run() before returning.
+	// called. This function is called by Run() before returning,
+	// see Retry to call it more than once.
 	Ping Ping
 
+	// Retry, if set, causes Ping to be called again after it fails
+	// until it succeeds or the policy's limits are reached. Ping is
+	// only called once if this is not set.
+	Retry *RetryPolicy
+
 	// Input is used to control the inputs to Run()
 	Input *ClientInput
 
@@ -54,36 +127,157 @@ type Service struct {
 
 // Run will run the Container.
 func (s *Service) Run() error {
+	return s.RunContext(context.Background())
+}
+
+// RunContext will run the Container. The provided context is used for
+// every call to Docker as well as being passed to Ping.
+func (s *Service) RunContext(ctx context.Context) error {
 	if s.Input == nil {
 		return ErrInputNotProvided
 	}
 
-	info, err := s.Client.RunContainer(context.Background(), s.Input)
+	info, err := s.Client.RunContainer(ctx, s.Input)
 	if err != nil {
 		return err
 	}
 	s.Container = info
 
+	// A service is expected to keep running so, unlike RunContainer,
+	// exiting at any point during Run is an error.
 	if s.Ping != nil {
-		input := &PingInput{
-			Service:   s,
-			Container: info,
-		}
-		if err := s.Ping(input); err != nil {
-			errs := errset.ErrSet{}
-			errs = append(errs, err)
-			errs = append(errs, s.Terminate())
-			return errs.ReturnValue()
+		err = s.ping(ctx, info)
+	} else if info.JSON.State != nil && !info.JSON.State.Running {
+		err = s.Client.startupError(ctx, info.ID(), info.JSON.State.ExitCode)
+	}
+	if err != nil {
+		cleanupctx, cancel := cleanupContext(ctx)
+		defer cancel()
+		// The error is returned as is, so it may be inspected with
+		// errors.Is, unless the Container could not be removed.
+		terminateErr := s.TerminateContext(cleanupctx)
+		if terminateErr == nil {
+			return err
 		}
+		return errset.ErrSet{err, terminateErr}
 	}
 
 	return nil
 }
 
+// ping calls Ping, retrying according to the Retry policy, while
+// watching for the Container to exit. If the Container exits Ping is
+// stopped and a *StartupError is returned.
+func (s *Service) ping(ctx context.Context, info *ContainerInfo) error {
+	pingctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	exited := make(chan int, 1)
+	go func() {
+		results, errs := s.Client.docker.ContainerWait(pingctx, info.ID(), WaitNotRunning)
+		select {
+		case body := <-results:
+			exited <- int(body.StatusCode)
+			cancel()
+		case <-errs:
+		}
+	}()
+
+	err := s.retry(pingctx, info)
+	if err == nil {
+		return nil
+	}
+	select {
+	case code := <-exited:
+		return s.Client.startupError(ctx, info.ID(), code)
+	default:
+	}
+
+	// Ping may fail because the Container exited before ContainerWait
+	// reported it so the Container is inspected before giving up.
+	inspectctx, cancelInspect := cleanupContext(ctx)
+	defer cancelInspect()
+	current, inspectErr := s.Client.ContainerInfo(inspectctx, info.ID())
+	if inspectErr == nil && current.JSON.State != nil && !current.JSON.State.Running {
+		return s.Client.startupError(ctx, info.ID(), current.JSON.State.ExitCode)
+	}
+	return err
+}
+
+// retry calls Ping until it succeeds or the limits of the Retry policy
+// are reached.
+func (s *Service) retry(ctx context.Context, info *ContainerInfo) error {
+	policy := s.Retry
+	if policy == nil {
+		return s.Ping(&PingInput{Context: ctx, Service: s, Container: info, Attempt: 1})
+	}
+
+	parent := ctx
+	if policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+	}
+
+	var last error
+	for attempt := 1; ; attempt++ {
+		err := s.Ping(&PingInput{Context: ctx, Service: s, Container: info, Attempt: attempt})
+		if err == nil {
+			return nil
+		}
+		// An attempt interrupted by the timeout is less useful to
+		// report than the failure before it.
+		if last == nil || ctx.Err() == nil {
+			last = err
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return fmt.Errorf("ping failed after %d attempts: %w", attempt, last)
+		}
+		select {
+		case <-ctx.Done():
+			if parent.Err() != nil {
+				return parent.Err()
+			}
+			return fmt.Errorf("ping failed after %d attempts: %w", attempt, last)
+		case <-time.After(policy.backoff(attempt)):
+		}
+	}
+}
+
 // Terminate terminates the Container and returns.
 func (s *Service) Terminate() error {
+	return s.TerminateContext(context.Background())
+}
+
+// TerminateContext terminates the Container using the provided context.
+func (s *Service) TerminateContext(ctx context.Context) error {
+	if s.Container == nil {
+		return ErrContainerNotStarted
+	}
+	return s.Client.RemoveContainer(ctx, s.Container.ID())
+}
+
+// Logs returns the output of the Container. See ContainerInfo.Logs.
+func (s *Service) Logs(ctx context.Context, options *LogOptions) (*LogOutput, error) {
+	if s.Container == nil {
+		return nil, ErrContainerNotStarted
+	}
+	return s.Container.Logs(ctx, options)
+}
+
+// Follow streams the output of the Container. See ContainerInfo.Follow.
+func (s *Service) Follow(ctx context.Context, options *LogOptions, stdout io.Writer, stderr io.Writer) error {
+	if s.Container == nil {
+		return ErrContainerNotStarted
+	}
+	return s.Container.Follow(ctx, options, stdout, stderr)
+}
+
+// FollowLines streams the output of the Container one line at a time.
+// See ContainerInfo.FollowLines.
+func (s *Service) FollowLines(ctx context.Context, options *LogOptions, lines chan<- LogLine) error {
 	if s.Container == nil {
 		return ErrContainerNotStarted
 	}
-	return s.Client.RemoveContainer(context.Background(), s.Container.ID())
+	return s.Container.FollowLines(ctx, options, lines)
 }
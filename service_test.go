@@ -1,10 +1,14 @@
 package dockertest
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
+	"github.com/opalmer/dockertest/fakeengine"
 	. "gopkg.in/check.v1"
 )
 
@@ -33,7 +37,7 @@ func (*ServiceTest) TestRunWithPing(c *C) {
 		port, err := input.Container.Port(80)
 		c.Assert(err, IsNil)
 		for {
-			con, err := net.Dial(string(port.Protocol), fmt.Sprintf("%s:%d", port.Address, port.Public))
+			con, err := net.Dial(string(port.Protocol), net.JoinHostPort(port.Address, fmt.Sprintf("%d", port.Public)))
 			if err != nil {
 				continue
 			}
@@ -58,3 +62,254 @@ func (*ServiceTest) TestErrorOnPingCallsTerminate(c *C) {
 	c.Assert(svc.Run(), ErrorMatches, "some error")
 	c.Assert(svc.Terminate(), IsNil)
 }
+
+func (*ServiceTest) TestRunFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	svc := dc.Service(NewClientInput(testImage))
+	pinged := false
+	svc.Ping = func(input *PingInput) error {
+		pinged = true
+		c.Assert(input.Container.State.Running, Equals, true)
+		return nil
+	}
+	c.Assert(svc.Run(), IsNil)
+	c.Assert(pinged, Equals, true)
+	c.Assert(svc.Terminate(), IsNil)
+	c.Assert(svc.Container.Refresh(), Equals, ErrContainerNotFound)
+}
+
+func (*ServiceTest) TestRunPingFailsFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	svc := dc.Service(NewClientInput(testImage))
+	svc.Ping = func(input *PingInput) error {
+		return errors.New("some error")
+	}
+	c.Assert(svc.Run(), ErrorMatches, "some error")
+	_, err := dc.ContainerInfo(context.Background(), svc.Container.ID())
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
+
+func (*ServiceTest) TestRunStartFailsFakeEngine(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{StartError: "failed to start"})
+
+	svc := dc.Service(NewClientInput(testImage))
+	c.Assert(svc.Run(), ErrorMatches, ".*failed to start")
+	c.Assert(svc.Container, IsNil)
+}
+
+func (*ServiceTest) TestRunContextCancelled(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddRemoteImage(testImage, &fakeengine.Image{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	svc := dc.Service(NewClientInput(testImage))
+	c.Assert(svc.RunContext(ctx), ErrorMatches, ".*context canceled.*")
+	c.Assert(server.HasImage(testImage), Equals, false)
+}
+
+func (*ServiceTest) TestRunContextPingTimesOut(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	svc := dc.Service(NewClientInput(testImage))
+	svc.Ping = func(input *PingInput) error {
+		<-input.Context.Done()
+		return input.Context.Err()
+	}
+	c.Assert(svc.RunContext(ctx), ErrorMatches, "context deadline exceeded")
+
+	// The container is still removed even though the context has expired.
+	_, err := dc.ContainerInfo(context.Background(), svc.Container.ID())
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
+
+func (*ServiceTest) TestTerminateContext(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	svc := dc.Service(NewClientInput(testImage))
+	c.Assert(svc.RunContext(context.Background()), IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Assert(svc.TerminateContext(ctx), ErrorMatches, ".*context canceled.*")
+	c.Assert(svc.TerminateContext(context.Background()), IsNil)
+}
+
+func (*ServiceTest) TestLogs(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{Stdout: []string{"ready"}})
+
+	svc := dc.Service(NewClientInput(testImage))
+	_, err := svc.Logs(context.Background(), nil)
+	c.Assert(err, Equals, ErrContainerNotStarted)
+	c.Assert(svc.Follow(context.Background(), nil, nil, nil), Equals, ErrContainerNotStarted)
+	c.Assert(svc.FollowLines(context.Background(), nil, nil), Equals, ErrContainerNotStarted)
+
+	c.Assert(svc.Run(), IsNil)
+	output, err := svc.Logs(context.Background(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(output.Stdout, Equals, "ready\n")
+
+	c.Assert(server.Exit(svc.Container.ID(), 0), IsNil)
+	stdout := &bytes.Buffer{}
+	c.Assert(svc.Follow(context.Background(), nil, stdout, stdout), IsNil)
+	c.Assert(stdout.String(), Equals, "ready\n")
+
+	lines := make(chan LogLine, 1)
+	c.Assert(svc.FollowLines(context.Background(), nil, lines), IsNil)
+	c.Assert(<-lines, DeepEquals, LogLine{Stream: StreamStdout, Text: "ready"})
+	c.Assert(svc.Terminate(), IsNil)
+}
+
+func (*ServiceTest) TestBackoff(c *C) {
+	policy := &RetryPolicy{InitialBackoff: time.Millisecond * 100, MaxBackoff: time.Millisecond * 300, Multiplier: 2}
+	c.Assert(policy.backoff(1), Equals, time.Millisecond*100)
+	c.Assert(policy.backoff(2), Equals, time.Millisecond*200)
+	c.Assert(policy.backoff(3), Equals, time.Millisecond*300)
+	c.Assert((&RetryPolicy{InitialBackoff: time.Second}).backoff(5), Equals, time.Second)
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.backoff(1)
+		c.Assert(delay >= time.Millisecond*50 && delay <= time.Millisecond*150, Equals, true)
+	}
+}
+
+func (*ServiceTest) TestRunRetry(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	svc := dc.Service(NewClientInput(testImage))
+	svc.Retry = &RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond}
+	attempts := []int{}
+	svc.Ping = func(input *PingInput) error {
+		attempts = append(attempts, input.Attempt)
+		if input.Attempt < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	}
+	c.Assert(svc.Run(), IsNil)
+	c.Assert(attempts, DeepEquals, []int{1, 2, 3})
+	c.Assert(svc.Terminate(), IsNil)
+}
+
+func (*ServiceTest) TestRunRetryMaxAttempts(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	svc := dc.Service(NewClientInput(testImage))
+	svc.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	svc.Ping = func(input *PingInput) error {
+		return fmt.Errorf("attempt %d failed", input.Attempt)
+	}
+	c.Assert(svc.Run(), ErrorMatches, "ping failed after 3 attempts: attempt 3 failed")
+	_, err := dc.ContainerInfo(context.Background(), svc.Container.ID())
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
+
+func (*ServiceTest) TestRunRetryTimeout(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	svc := dc.Service(NewClientInput(testImage))
+	svc.Retry = &RetryPolicy{Timeout: time.Millisecond * 50, InitialBackoff: time.Millisecond * 10}
+	svc.Ping = func(input *PingInput) error {
+		select {
+		case <-input.Context.Done():
+			return input.Context.Err()
+		case <-time.After(time.Millisecond):
+			return errors.New("not ready")
+		}
+	}
+	c.Assert(svc.Run(), ErrorMatches, "ping failed after [0-9]+ attempts: not ready")
+}
+
+func (*ServiceTest) TestRunContainerExits(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	svc := dc.Service(NewClientInput(testImage))
+	svc.Retry = NewRetryPolicy()
+	svc.Ping = func(input *PingInput) error {
+		if input.Attempt == 1 {
+			c.Assert(server.Exit(input.Container.ID(), 3), IsNil)
+		}
+		<-input.Context.Done()
+		return input.Context.Err()
+	}
+	start := time.Now()
+	err := svc.Run()
+	c.Assert(errors.Is(err, ErrContainerExited), Equals, true)
+	c.Assert(err, ErrorMatches, "container exited with code 3")
+	var startup *StartupError
+	c.Assert(errors.As(err, &startup), Equals, true)
+	c.Assert(startup.ID, Equals, svc.Container.ID())
+	c.Assert(time.Since(start) < time.Second*5, Equals, true)
+	_, err = dc.ContainerInfo(context.Background(), svc.Container.ID())
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
+
+func (*ServiceTest) TestRunContainerExitsWithoutRetry(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{Exits: true, ExitCode: 1, Stderr: []string{"bad config"}})
+
+	svc := dc.Service(NewClientInput(testImage))
+	svc.Ping = func(input *PingInput) error {
+		<-input.Context.Done()
+		return input.Context.Err()
+	}
+	c.Assert(svc.Run(), ErrorMatches, "container exited with code 1\nlast log lines:\n\tbad config")
+}
+
+func (*ServiceTest) TestRunContainerExitsBeforeWait(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	// Ping fails as soon as the container exits, which may be before
+	// ContainerWait reports the exit.
+	svc := dc.Service(NewClientInput(testImage))
+	svc.Ping = func(input *PingInput) error {
+		c.Assert(server.Exit(input.Container.ID(), 4), IsNil)
+		return errors.New("connection refused")
+	}
+	err := svc.Run()
+	var startup *StartupError
+	c.Assert(errors.As(err, &startup), Equals, true, Commentf("%v", err))
+	c.Assert(startup.ExitCode, Equals, 4)
+}
+
+func (*ServiceTest) TestRunContainerExitsWithoutPing(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{Exits: true, ExitCode: 2})
+
+	svc := dc.Service(NewClientInput(testImage))
+	err := svc.Run()
+	c.Assert(errors.Is(err, ErrContainerExited), Equals, true)
+	c.Assert(err, ErrorMatches, "container exited with code 2")
+	_, err = dc.ContainerInfo(context.Background(), svc.Container.ID())
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
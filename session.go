@@ -0,0 +1,210 @@
+package dockertest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+const (
+	// HostnameLabel is the label used to record the host which created
+	// a container, network or volume.
+	HostnameLabel = "dockertest.hostname"
+
+	// PIDLabel is the label used to record the process which created a
+	// container, network or volume.
+	PIDLabel = "dockertest.pid"
+)
+
+// exitProcess is called once the resources belonging to a session have
+// been removed after the process received a signal. The exit code is
+// the one a shell reports for a process killed by the signal.
+var exitProcess = func(sig os.Signal) {
+	if number, ok := sig.(syscall.Signal); ok {
+		os.Exit(128 + int(number))
+	}
+	os.Exit(1)
+}
+
+// processSessionID is the id shared by the Session of every DockerClient
+// created by this process.
+var (
+	processSessionOnce sync.Once
+	processSessionID   string
+)
+
+// sessionID returns processSessionID, generating it on the first call.
+func sessionID() string {
+	processSessionOnce.Do(func() {
+		id, err := randomName("")
+		if err != nil {
+			id = strconv.FormatInt(int64(os.Getpid()), 16)
+		}
+		processSessionID = id
+	})
+	return processSessionID
+}
+
+// Session identifies the containers, networks and volumes created by a
+// process so they can be removed together. Each DockerClient has its own
+// Session, with the same id for every client in the process, which labels
+// everything the client creates with the session id, hostname and process
+// id. Use Run from TestMain to remove
+// everything once the tests finish or are interrupted:
+//
+//	func TestMain(m *testing.M) {
+//	    os.Exit(client.Session().Run(m))
+//	}
+type Session struct {
+	ID       string
+	Hostname string
+	PID      int
+	client   *DockerClient
+
+	mu     sync.Mutex
+	reaper net.Conn
+}
+
+func newSession(client *DockerClient) *Session {
+	hostname, _ := os.Hostname() // nolint: errcheck
+	return &Session{ID: sessionID(), Hostname: hostname, PID: os.Getpid(), client: client}
+}
+
+// Labels returns the labels applied to resources created in the session.
+func (s *Session) Labels() map[string]string {
+	return map[string]string{
+		SessionLabel:  s.ID,
+		HostnameLabel: s.Hostname,
+		PIDLabel:      strconv.Itoa(s.PID),
+	}
+}
+
+// apply returns a copy of labels with the session's labels added.
+func (s *Session) apply(labels map[string]string) map[string]string {
+	applied := map[string]string{}
+	for key, value := range labels {
+		applied[key] = value
+	}
+	for key, value := range s.Labels() {
+		applied[key] = value
+	}
+	return applied
+}
+
+// Cleanup removes every container, network and volume created in the
+// session.
+func (s *Session) Cleanup(ctx context.Context) (*CleanupReport, error) {
+	return s.client.Cleanup(ctx, &CleanupPolicy{Session: s.ID})
+}
+
+// CleanupOnSignal removes every resource created in the session if the
+// process receives SIGINT or SIGTERM, the process then exits as it would
+// have otherwise. Signals are handled once for the whole process so every
+// Session which called CleanupOnSignal, possibly using different Docker
+// hosts, is cleaned up before exiting. Call the returned function to stop
+// handling signals for this Session.
+func (s *Session) CleanupOnSignal() func() {
+	cleanupHandler.add(s)
+	var once sync.Once
+	return func() {
+		once.Do(func() { cleanupHandler.remove(s) })
+	}
+}
+
+// cleanupHandler is the process wide handler used by CleanupOnSignal.
+var cleanupHandler = &signalHandler{sessions: map[*Session]int{}}
+
+// signalHandler cleans up the registered sessions when the process
+// receives a signal. Signals are only handled while at least one session
+// is registered.
+type signalHandler struct {
+	mu       sync.Mutex
+	sessions map[*Session]int
+	signals  chan os.Signal
+}
+
+func (h *signalHandler) add(s *Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.sessions) == 0 {
+		h.signals = make(chan os.Signal, 1)
+		signal.Notify(h.signals, os.Interrupt, syscall.SIGTERM)
+		go h.wait(h.signals)
+	}
+	h.sessions[s]++
+}
+
+func (h *signalHandler) remove(s *Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// The session is no longer registered if a signal was handled.
+	if _, ok := h.sessions[s]; !ok {
+		return
+	}
+	if h.sessions[s]--; h.sessions[s] > 0 {
+		return
+	}
+	delete(h.sessions, s)
+	if len(h.sessions) == 0 {
+		// No more signals are delivered once Stop returns so closing
+		// the channel is safe.
+		signal.Stop(h.signals)
+		close(h.signals)
+	}
+}
+
+func (h *signalHandler) wait(signals chan os.Signal) {
+	sig, ok := <-signals
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	signal.Stop(signals)
+	sessions := []*Session{}
+	for session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	h.sessions = map[*Session]int{}
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	var wg sync.WaitGroup
+	for _, session := range sessions {
+		wg.Add(1)
+		go func(session *Session) {
+			defer wg.Done()
+			if _, err := session.Cleanup(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "dockertest: cleanup of session %s failed: %s\n", session.ID, err)
+			}
+		}(session)
+	}
+	wg.Wait()
+	cancel()
+	exitProcess(sig)
+}
+
+// Run runs the tests, normally a *testing.M, while handling signals with
+// CleanupOnSignal and then removes every resource created in the
+// session. It returns the exit code to pass to os.Exit which is non-zero
+// if the tests or the cleanup failed.
+func (s *Session) Run(m interface{ Run() int }) int {
+	stop := s.CleanupOnSignal()
+	defer stop()
+
+	code := m.Run()
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if _, err := s.Cleanup(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "dockertest: cleanup of session %s failed: %s\n", s.ID, err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
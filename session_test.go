@@ -0,0 +1,202 @@
+package dockertest
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type SessionTest struct{}
+
+var _ = Suite(&SessionTest{})
+
+// fakeTests is used in place of *testing.M.
+type fakeTests struct {
+	run func() int
+}
+
+func (t *fakeTests) Run() int {
+	return t.run()
+}
+
+func (s *SessionTest) TestNewSession(c *C) {
+	first := NewClientWithEngine(&stubEngine{}).Session()
+	second := NewClientWithEngine(&stubEngine{}).Session()
+	c.Assert(first.ID, Not(Equals), "")
+	c.Assert(first.ID, Equals, second.ID)
+	c.Assert(first, Not(Equals), second)
+	c.Assert(first.PID, Equals, os.Getpid())
+
+	hostname, err := os.Hostname()
+	c.Assert(err, IsNil)
+	c.Assert(first.Labels(), DeepEquals, map[string]string{
+		SessionLabel:  first.ID,
+		HostnameLabel: hostname,
+		PIDLabel:      strconv.Itoa(os.Getpid()),
+	})
+}
+
+func (s *SessionTest) TestSessionLabelsApplied(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	session := dc.Session()
+
+	input := NewClientInput(testImage)
+	input.Mounts = []Mount{VolumeMount("", "/data")}
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	for key, value := range session.Labels() {
+		c.Assert(info.HasLabel(key, value), Equals, true)
+	}
+	c.Assert(info.HasLabel("dockertest", "1"), Equals, true)
+
+	// The input is not modified.
+	_, ok := input.Labels[SessionLabel]
+	c.Assert(ok, Equals, false)
+
+	// Volumes created by Docker for the container are also labeled.
+	volume, err := dc.VolumeInfo(context.Background(), info.JSON.Mounts[0].Name)
+	c.Assert(err, IsNil)
+	c.Assert(volume.JSON.Labels[SessionLabel], Equals, session.ID)
+}
+
+func (s *SessionTest) TestSessionCleanup(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	network, err := dc.CreateNetwork(context.Background(), NewNetworkInput(""))
+	c.Assert(err, IsNil)
+	volume, err := dc.CreateVolume(context.Background(), NewVolumeInput(""))
+	c.Assert(err, IsNil)
+
+	report, err := dc.Session().Cleanup(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(report, DeepEquals, &CleanupReport{
+		Containers: []string{info.ID()},
+		Networks:   []string{network.ID()},
+		Volumes:    []string{volume.Name()},
+	})
+}
+
+func (s *SessionTest) TestSessionCleanupWithoutDefaultLabel(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	info, err := dc.RunContainer(context.Background(), &ClientInput{Image: testImage, Ports: NewPorts()})
+	c.Assert(err, IsNil)
+	c.Assert(info.HasLabel("dockertest", "1"), Equals, false)
+	network, err := dc.CreateNetwork(context.Background(), &NetworkInput{})
+	c.Assert(err, IsNil)
+
+	report, err := dc.Session().Cleanup(context.Background())
+	c.Assert(err, IsNil)
+	c.Assert(report.Containers, DeepEquals, []string{info.ID()})
+	c.Assert(report.Networks, DeepEquals, []string{network.ID()})
+}
+
+func (s *SessionTest) TestSessionRun(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	var id string
+	code := dc.Session().Run(&fakeTests{run: func() int {
+		info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+		c.Assert(err, IsNil)
+		id = info.ID()
+		return 3
+	}})
+	c.Assert(code, Equals, 3)
+	_, err := dc.ContainerInfo(context.Background(), id)
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
+
+func (s *SessionTest) TestCleanupOnSignal(c *C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("signals cannot be sent to the current process on windows")
+	}
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+
+	exited := make(chan os.Signal, 1)
+	defer func(original func(os.Signal)) { exitProcess = original }(exitProcess)
+	exitProcess = func(sig os.Signal) { exited <- sig }
+
+	stop := dc.Session().CleanupOnSignal()
+	defer stop()
+	process, err := os.FindProcess(os.Getpid())
+	c.Assert(err, IsNil)
+	c.Assert(process.Signal(syscall.SIGTERM), IsNil)
+	select {
+	case sig := <-exited:
+		c.Assert(sig, Equals, syscall.SIGTERM)
+	case <-time.After(time.Second * 5):
+		c.Fatal("signal was not handled")
+	}
+	_, err = dc.ContainerInfo(context.Background(), info.ID())
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
+
+func (s *SessionTest) TestCleanupOnSignalMultipleClients(c *C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("signals cannot be sent to the current process on windows")
+	}
+	clients := []*DockerClient{}
+	infos := []*ContainerInfo{}
+	for i := 0; i < 2; i++ {
+		dc, server := newFakeClient(c)
+		defer server.Close() // nolint: errcheck
+		server.AddImage(testImage, &fakeengine.Image{})
+		info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+		c.Assert(err, IsNil)
+		clients = append(clients, dc)
+		infos = append(infos, info)
+	}
+
+	exited := make(chan os.Signal, 1)
+	defer func(original func(os.Signal)) { exitProcess = original }(exitProcess)
+	exitProcess = func(sig os.Signal) { exited <- sig }
+
+	for _, dc := range clients {
+		stop := dc.Session().CleanupOnSignal()
+		defer stop()
+	}
+	process, err := os.FindProcess(os.Getpid())
+	c.Assert(err, IsNil)
+	c.Assert(process.Signal(syscall.SIGTERM), IsNil)
+	select {
+	case <-exited:
+	case <-time.After(time.Second * 5):
+		c.Fatal("signal was not handled")
+	}
+	for i, dc := range clients {
+		_, err = dc.ContainerInfo(context.Background(), infos[i].ID())
+		c.Assert(err, Equals, ErrContainerNotFound)
+	}
+}
+
+func (s *SessionTest) TestCleanupOnSignalStop(c *C) {
+	session := NewClientWithEngine(&stubEngine{}).Session()
+	first := session.CleanupOnSignal()
+	second := session.CleanupOnSignal()
+	first()
+	first()
+	c.Assert(cleanupHandler.sessions[session], Equals, 1)
+	second()
+	_, ok := cleanupHandler.sessions[session]
+	c.Assert(ok, Equals, false)
+}
@@ -3,6 +3,7 @@ package dockertest
 import (
 	"testing"
 
+	"github.com/opalmer/dockertest/fakeengine"
 	"gopkg.in/check.v1"
 )
 
@@ -11,3 +12,14 @@ const testImage = "nginx:mainline-alpine"
 func Test(t *testing.T) {
 	check.TestingT(t)
 }
+
+// newFakeClient returns a *DockerClient which talks to an in-process
+// fake engine rather than a real Docker daemon. The returned server
+// should be closed by the caller.
+func newFakeClient(c *check.C) (*DockerClient, *fakeengine.Server) {
+	server, err := fakeengine.NewServer()
+	c.Assert(err, check.IsNil)
+	docker, err := server.Client()
+	c.Assert(err, check.IsNil)
+	return NewClientWithEngine(docker), server
+}
@@ -0,0 +1,107 @@
+package dockertest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// startupLogLines is the number of log lines included in a StartupError.
+const startupLogLines = 20
+
+// StartupError is returned when a container exits while it is starting
+// up, either during ClientInput.StartupPeriod or before Service.Ping
+// succeeds. It wraps ErrContainerExited.
+type StartupError struct {
+	// ID is the id of the container.
+	ID string
+
+	// ExitCode is the exit code of the container and OOMKilled is true
+	// if the kernel killed it for running out of memory.
+	ExitCode  int
+	OOMKilled bool
+
+	// Logs contains up to the last 20 lines the container wrote to
+	// stdout and stderr.
+	Logs []string
+
+	// State is the state of the container after it exited. It is nil
+	// if the container could not be inspected, for example because it
+	// was removed automatically.
+	State *types.ContainerState
+}
+
+func (e *StartupError) Error() string {
+	message := fmt.Sprintf("%s with code %d", ErrContainerExited, e.ExitCode)
+	if e.OOMKilled {
+		message += " (OOM killed)"
+	}
+	if e.State != nil && e.State.Error != "" {
+		message += ": " + e.State.Error
+	}
+	if len(e.Logs) > 0 {
+		message += "\nlast log lines:\n\t" + strings.Join(e.Logs, "\n\t")
+	}
+	return message
+}
+
+// Unwrap returns ErrContainerExited so StartupError may be matched using
+// errors.Is.
+func (e *StartupError) Unwrap() error {
+	return ErrContainerExited
+}
+
+// startupError collects the diagnostics of a container which exited with
+// code. Failures to collect them are ignored because the exit is the
+// more useful error to report.
+func (d *DockerClient) startupError(ctx context.Context, id string, code int) *StartupError {
+	ctx, cancel := cleanupContext(ctx)
+	defer cancel()
+
+	result := &StartupError{ID: id, ExitCode: code}
+	info, err := d.ContainerInfo(ctx, id)
+	if err != nil {
+		return result
+	}
+	result.State = info.JSON.State
+	if result.State != nil {
+		result.ExitCode = result.State.ExitCode
+		result.OOMKilled = result.State.OOMKilled
+	}
+
+	output := &bytes.Buffer{}
+	logctx, cancel := d.callContext(ctx)
+	defer cancel()
+	if err := info.copyLogs(logctx, &LogOptions{Tail: startupLogLines}, false, output, output); err == nil {
+		text := strings.TrimRight(output.String(), "\n")
+		if text != "" {
+			result.Logs = strings.Split(text, "\n")
+		}
+	}
+	return result
+}
+
+// waitForStartup waits up to period for the container to exit and
+// returns a *StartupError if it does.
+func (d *DockerClient) waitForStartup(ctx context.Context, id string, period time.Duration) error {
+	waitctx, cancel := context.WithTimeout(ctx, period)
+	defer cancel()
+
+	results, errs := d.docker.ContainerWait(waitctx, id, WaitNotRunning)
+	select {
+	case body := <-results:
+		return d.startupError(ctx, id, int(body.StatusCode))
+	case err := <-errs:
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if waitctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+}
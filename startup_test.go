@@ -0,0 +1,111 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type StartupTest struct{}
+
+var _ = Suite(&StartupTest{})
+
+func (s *StartupTest) TestError(c *C) {
+	err := &StartupError{ExitCode: 137, OOMKilled: true}
+	c.Assert(err, ErrorMatches, "container exited with code 137 \\(OOM killed\\)")
+	c.Assert(errors.Is(err, ErrContainerExited), Equals, true)
+
+	err = &StartupError{
+		ExitCode: 1,
+		Logs:     []string{"starting", "fatal: bad config"},
+		State:    &types.ContainerState{Error: "failed"},
+	}
+	c.Assert(err.Error(), Equals, "container exited with code 1: failed\nlast log lines:\n\tstarting\n\tfatal: bad config")
+}
+
+func (s *StartupTest) TestRunContainerStartupPeriod(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	input := NewClientInput(testImage)
+	input.StartupPeriod = time.Millisecond * 50
+
+	start := time.Now()
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	c.Assert(info.JSON.State.Running, Equals, true)
+	c.Assert(time.Since(start) >= input.StartupPeriod, Equals, true)
+}
+
+func (s *StartupTest) TestRunContainerExits(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	lines := []string{}
+	for i := 0; i < startupLogLines+5; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	server.AddImage(testImage, &fakeengine.Image{Exits: true, ExitCode: 3, Stdout: lines})
+	input := NewClientInput(testImage)
+	input.StartupPeriod = time.Minute
+
+	start := time.Now()
+	_, err := dc.RunContainer(context.Background(), input)
+	c.Assert(time.Since(start) < time.Second*5, Equals, true)
+	var startup *StartupError
+	c.Assert(errors.As(err, &startup), Equals, true)
+	c.Assert(startup.ExitCode, Equals, 3)
+	c.Assert(startup.OOMKilled, Equals, false)
+	c.Assert(startup.Logs, DeepEquals, lines[5:])
+	c.Assert(startup.State, NotNil)
+	c.Assert(startup.State.Running, Equals, false)
+
+	// The container is removed once the diagnostics are collected.
+	_, err = dc.ContainerInfo(context.Background(), startup.ID)
+	c.Assert(err, Equals, ErrContainerNotFound)
+}
+
+func (s *StartupTest) TestServiceOOMKilled(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	svc := dc.Service(NewClientInput(testImage))
+	svc.Ping = func(input *PingInput) error {
+		c.Assert(server.OOMKill(input.Container.ID()), IsNil)
+		<-input.Context.Done()
+		return input.Context.Err()
+	}
+	var startup *StartupError
+	c.Assert(errors.As(svc.Run(), &startup), Equals, true)
+	c.Assert(startup.ExitCode, Equals, 137)
+	c.Assert(startup.OOMKilled, Equals, true)
+}
+
+func (s *StartupTest) TestRunContainerWithoutStartupPeriod(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{Exits: true, ExitCode: 3})
+
+	// One-shot containers are expected to exit.
+	info, err := dc.RunContainer(context.Background(), NewClientInput(testImage))
+	c.Assert(err, IsNil)
+	c.Assert(info.ExitCode(), Equals, 3)
+}
+
+func (s *StartupTest) TestRunContainerStartupContext(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+	input := NewClientInput(testImage)
+	input.StartupPeriod = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	_, err := dc.RunContainer(ctx, input)
+	c.Assert(err, Equals, context.DeadlineExceeded)
+}
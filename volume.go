@@ -0,0 +1,145 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+var (
+	// ErrVolumeNotFound is returned by VolumeInfo if we were unable to
+	// find the requested volume.
+	ErrVolumeNotFound = errors.New("failed to locate the volume")
+)
+
+// VolumeInput is used to provide inputs to the CreateVolume and
+// ListVolumes functions.
+type VolumeInput struct {
+	// Name is the name of the volume. Docker generates a unique name if
+	// one is not provided.
+	Name string
+
+	// Driver is the volume driver, Docker uses local if not provided.
+	Driver string
+
+	// Options are passed to the volume driver.
+	Options map[string]string
+
+	// Labels are applied to the volume.
+	Labels map[string]string
+}
+
+// NewVolumeInput produces a *VolumeInput struct. The dockertest label
+// is applied so the volume can be found and cleaned up later.
+func NewVolumeInput(name string) *VolumeInput {
+	return &VolumeInput{
+		Name:   name,
+		Labels: map[string]string{"dockertest": "1"},
+	}
+}
+
+// VolumesCreateBody converts *VolumeInput into the options which may be
+// passed to the VolumeCreate() API call.
+func (v *VolumeInput) VolumesCreateBody() volume.VolumesCreateBody {
+	return volume.VolumesCreateBody{
+		Name:       v.Name,
+		Driver:     v.Driver,
+		DriverOpts: v.Options,
+		Labels:     v.Labels,
+	}
+}
+
+// FilterArgs converts *VolumeInput into a filters.Args struct which may
+// be used with the docker client directly.
+func (v *VolumeInput) FilterArgs() filters.Args {
+	args := filters.NewArgs()
+	if v.Name != "" {
+		args.Add("name", v.Name)
+	}
+	if v.Driver != "" {
+		args.Add("driver", v.Driver)
+	}
+	for key, value := range v.Labels {
+		args.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
+	return args
+}
+
+// VolumeInfo provides a wrapper around information about a volume.
+type VolumeInfo struct {
+	JSON   types.Volume
+	client *DockerClient
+}
+
+// Name is a shortcut function to return the volume's name.
+func (v *VolumeInfo) Name() string {
+	return v.JSON.Name
+}
+
+// Remove removes the volume.
+func (v *VolumeInfo) Remove(ctx context.Context) error {
+	return v.client.RemoveVolume(ctx, v.Name())
+}
+
+// CreateVolume creates a new volume which may be mounted into containers
+// using VolumeMount. The volume is labeled with the client's Session.
+func (d *DockerClient) CreateVolume(ctx context.Context, input *VolumeInput) (*VolumeInfo, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	body := input.VolumesCreateBody()
+	body.Labels = d.session.apply(body.Labels)
+	created, err := d.docker.VolumeCreate(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	return &VolumeInfo{JSON: created, client: d}, nil
+}
+
+// VolumeInfo retrieves a single volume by name.
+func (d *DockerClient) VolumeInfo(ctx context.Context, name string) (*VolumeInfo, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	inspection, err := d.docker.VolumeInspect(ctx, name)
+	if client.IsErrNotFound(err) {
+		return nil, ErrVolumeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &VolumeInfo{JSON: inspection, client: d}, nil
+}
+
+// ListVolumes returns the volumes matching the provided input.
+func (d *DockerClient) ListVolumes(ctx context.Context, input *VolumeInput) ([]*VolumeInfo, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	listed, err := d.docker.VolumeList(ctx, input.FilterArgs())
+	if err != nil {
+		return nil, err
+	}
+	volumes := []*VolumeInfo{}
+	for _, entry := range listed.Volumes {
+		if entry != nil {
+			volumes = append(volumes, &VolumeInfo{JSON: *entry, client: d})
+		}
+	}
+	return volumes, nil
+}
+
+// RemoveVolume will delete the requested volume. Removing a volume which
+// does not exist is not an error but volumes which are still used by a
+// container, even a stopped one, cannot be removed.
+func (d *DockerClient) RemoveVolume(ctx context.Context, name string) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	err := d.docker.VolumeRemove(ctx, name, false)
+	if client.IsErrNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package dockertest
+
+import (
+	"context"
+
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type VolumeTest struct{}
+
+var _ = Suite(&VolumeTest{})
+
+func (s *VolumeTest) TestNewVolumeInput(c *C) {
+	input := NewVolumeInput("data")
+	c.Assert(input, DeepEquals, &VolumeInput{
+		Name:   "data",
+		Labels: map[string]string{"dockertest": "1"},
+	})
+	body := input.VolumesCreateBody()
+	c.Assert(body.Name, Equals, "data")
+	c.Assert(body.Labels, DeepEquals, input.Labels)
+	c.Assert(input.FilterArgs().Get("label"), DeepEquals, []string{"dockertest=1"})
+}
+
+func (s *VolumeTest) TestCreateVolume(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+
+	created, err := dc.CreateVolume(context.Background(), NewVolumeInput("data"))
+	c.Assert(err, IsNil)
+	c.Assert(created.Name(), Equals, "data")
+	c.Assert(created.JSON.Labels["dockertest"], Equals, "1")
+	c.Assert(created.JSON.Labels[SessionLabel], Equals, dc.Session().ID)
+
+	generated, err := dc.CreateVolume(context.Background(), NewVolumeInput(""))
+	c.Assert(err, IsNil)
+	c.Assert(generated.Name(), Not(Equals), "")
+
+	volumes, err := dc.ListVolumes(context.Background(), NewVolumeInput(""))
+	c.Assert(err, IsNil)
+	c.Assert(volumes, HasLen, 2)
+
+	info, err := dc.VolumeInfo(context.Background(), "data")
+	c.Assert(err, IsNil)
+	c.Assert(info.Name(), Equals, "data")
+	_, err = dc.VolumeInfo(context.Background(), "missing")
+	c.Assert(err, Equals, ErrVolumeNotFound)
+}
+
+func (s *VolumeTest) TestRemoveVolume(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	server.AddImage(testImage, &fakeengine.Image{})
+
+	created, err := dc.CreateVolume(context.Background(), NewVolumeInput("data"))
+	c.Assert(err, IsNil)
+	input := NewClientInput(testImage)
+	input.Mounts = []Mount{VolumeMount("data", "/data")}
+	info, err := dc.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+
+	// Volumes which are in use cannot be removed.
+	c.Assert(created.Remove(context.Background()), ErrorMatches, ".*volume is in use.*")
+
+	// Named volumes are not removed with the container.
+	c.Assert(dc.RemoveContainer(context.Background(), info.ID()), IsNil)
+	_, err = dc.VolumeInfo(context.Background(), "data")
+	c.Assert(err, IsNil)
+	c.Assert(created.Remove(context.Background()), IsNil)
+	_, err = dc.VolumeInfo(context.Background(), "data")
+	c.Assert(err, Equals, ErrVolumeNotFound)
+
+	// Removing a volume which does not exist is not an error.
+	c.Assert(dc.RemoveVolume(context.Background(), "data"), IsNil)
+}
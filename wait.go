@@ -0,0 +1,67 @@
+package dockertest
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Conditions which may be passed to ContainerInfo.Wait.
+const (
+	// WaitNotRunning waits until the container is not running. Wait
+	// returns immediately if the container has already stopped or has
+	// not been started.
+	WaitNotRunning = container.WaitConditionNotRunning
+
+	// WaitNextExit waits for the next time the container exits.
+	WaitNextExit = container.WaitConditionNextExit
+
+	// WaitRemoved waits for the container to be removed.
+	WaitRemoved = container.WaitConditionRemoved
+)
+
+// WaitResult is returned by ContainerInfo.Wait.
+type WaitResult struct {
+	// ExitCode is the exit code of the container.
+	ExitCode int
+
+	// Error is the error message Docker recorded for the container,
+	// such as a failure to start, if any. It is always empty when
+	// waiting for the container to be removed.
+	Error string
+}
+
+// Wait blocks until the container reaches the requested condition, or
+// WaitNotRunning if condition is empty, and returns its exit status.
+// Unless waiting for removal the ContainerInfo is refreshed afterwards
+// so State reflects the stopped container.
+func (c *ContainerInfo) Wait(ctx context.Context, condition container.WaitCondition) (*WaitResult, error) {
+	if condition == "" {
+		condition = WaitNotRunning
+	}
+
+	// The per-call timeout is not applied because waiting for a
+	// container is expected to take longer than a single API call.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results, errs := c.client.docker.ContainerWait(ctx, c.ID(), condition)
+
+	result := &WaitResult{}
+	select {
+	case body := <-results:
+		result.ExitCode = int(body.StatusCode)
+	case err := <-errs:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if condition == WaitRemoved {
+		return result, nil
+	}
+	if err := c.RefreshContext(ctx); err != nil {
+		return nil, err
+	}
+	result.Error = c.ErrorMessage()
+	return result, nil
+}
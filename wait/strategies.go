@@ -0,0 +1,216 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/opalmer/dockertest"
+)
+
+var (
+	// ErrNoHealthcheck is returned by the Ping produced by Healthy if
+	// the container does not have a healthcheck.
+	ErrNoHealthcheck = dockertest.ErrNoHealthcheck
+
+	// ErrLogNotFound is returned, wrapped by ErrTimeout, by the Ping
+	// produced by Log if no line matched.
+	ErrLogNotFound = errors.New("no matching log line found")
+)
+
+// address returns the host and port which the container's internal port
+// is published on.
+func address(input *dockertest.PingInput, internal int) (string, error) {
+	port, err := input.Container.Port(internal)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(port.Address, strconv.Itoa(int(port.Public))), nil
+}
+
+// TCP produces a Ping which waits until a connection can be made to the
+// host port which internal is published on.
+func TCP(internal int, opts ...Option) dockertest.Ping {
+	return func(input *dockertest.PingInput) error {
+		target, err := address(input, internal)
+		if err != nil {
+			return err
+		}
+		return poll(input, opts, func(ctx context.Context) error {
+			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", target)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		})
+	}
+}
+
+// HTTPInput is used to provide inputs to the HTTP function.
+type HTTPInput struct {
+	// Port is the internal port of the container to send requests to.
+	Port int
+
+	// Path is the path to request, for example /health.
+	Path string
+
+	// Method is the HTTP method to use, GET is used if not provided.
+	Method string
+
+	// HTTPS sends the request using https rather than http.
+	HTTPS bool
+
+	// StatusCode is the response status to wait for. Any 2xx status is
+	// accepted if not provided.
+	StatusCode int
+
+	// Body, if set, must match the response body.
+	Body *regexp.Regexp
+
+	// Client is used to send the requests, http.DefaultClient is used
+	// if not provided.
+	Client *http.Client
+}
+
+// NewHTTPInput produces a *HTTPInput struct which waits for a 200
+// response to a GET request.
+func NewHTTPInput(port int, path string) *HTTPInput {
+	return &HTTPInput{Port: port, Path: path, Method: http.MethodGet, StatusCode: http.StatusOK}
+}
+
+// check sends a single request to target.
+func (h *HTTPInput) check(ctx context.Context, target string) error {
+	scheme := "http"
+	if h.HTTPS {
+		scheme = "https"
+	}
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := h.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	request, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s", scheme, target, path), nil)
+	if err != nil {
+		return err
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if h.StatusCode != 0 && response.StatusCode != h.StatusCode {
+		return fmt.Errorf("unexpected status %d, expected %d", response.StatusCode, h.StatusCode)
+	}
+	if h.StatusCode == 0 && (response.StatusCode < 200 || response.StatusCode > 299) {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	if h.Body != nil && !h.Body.Match(body) {
+		return fmt.Errorf("response body does not match %q", h.Body)
+	}
+	return nil
+}
+
+// HTTP produces a Ping which waits until the request described by input
+// receives the expected response.
+func HTTP(input *HTTPInput, opts ...Option) dockertest.Ping {
+	return func(ping *dockertest.PingInput) error {
+		target, err := address(ping, input.Port)
+		if err != nil {
+			return err
+		}
+		return poll(ping, opts, func(ctx context.Context) error {
+			return input.check(ctx, target)
+		})
+	}
+}
+
+// Log produces a Ping which waits until a line of the container's
+// stdout or stderr matches pattern.
+func Log(pattern *regexp.Regexp, opts ...Option) dockertest.Ping {
+	return func(input *dockertest.PingInput) error {
+		return poll(input, opts, func(ctx context.Context) error {
+			output, err := input.Container.Logs(ctx, nil)
+			if err != nil {
+				return err
+			}
+			for _, stream := range []string{output.Stdout, output.Stderr} {
+				for _, line := range strings.Split(stream, "\n") {
+					if pattern.MatchString(line) {
+						return nil
+					}
+				}
+			}
+			return ErrLogNotFound
+		})
+	}
+}
+
+// Healthy produces a Ping which waits until Docker reports the container
+// as healthy. The container's image or ClientInput must define a
+// healthcheck.
+func Healthy(opts ...Option) dockertest.Ping {
+	return func(input *dockertest.PingInput) error {
+		return poll(input, opts, func(ctx context.Context) error {
+			// A copy is refreshed so the Ping does not modify the
+			// ContainerInfo while other Pings may be reading it.
+			info := *input.Container
+			if err := info.RefreshContext(ctx); err != nil {
+				return err
+			}
+			health, err := info.Health()
+			if err != nil {
+				return fatal{err}
+			}
+			if health.Status != dockertest.HealthHealthy {
+				return fmt.Errorf("container is %s", health.Status)
+			}
+			return nil
+		})
+	}
+}
+
+// Exec produces a Ping which waits until cmd exits with a zero exit code
+// when run inside of the container.
+func Exec(cmd []string, opts ...Option) dockertest.Ping {
+	return func(input *dockertest.PingInput) error {
+		return poll(input, opts, func(ctx context.Context) error {
+			result, err := input.Container.Exec(ctx, cmd, nil)
+			if err != nil {
+				return err
+			}
+			if result.ExitCode != 0 {
+				return fmt.Errorf("%s exited with %d: %s",
+					strings.Join(cmd, " "), result.ExitCode, strings.TrimSpace(result.Stderr))
+			}
+			return nil
+		})
+	}
+}
+
+// File produces a Ping which waits until path exists inside of the
+// container.
+func File(path string, opts ...Option) dockertest.Ping {
+	return func(input *dockertest.PingInput) error {
+		return poll(input, opts, func(ctx context.Context) error {
+			return input.Container.CopyFrom(ctx, path, dockertest.ToWriter(ioutil.Discard))
+		})
+	}
+}
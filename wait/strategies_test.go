@@ -0,0 +1,152 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/opalmer/dockertest"
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+const testImage = "nginx:mainline-alpine"
+
+type StrategiesTest struct {
+	server *fakeengine.Server
+	client *dockertest.DockerClient
+}
+
+var _ = Suite(&StrategiesTest{})
+
+// quick keeps failing tests from waiting for DefaultTimeout.
+var quick = []Option{WithInterval(time.Millisecond * 10), WithTimeout(time.Millisecond * 200)}
+
+func (s *StrategiesTest) SetUpTest(c *C) {
+	server, err := fakeengine.NewServer()
+	c.Assert(err, IsNil)
+	docker, err := server.Client()
+	c.Assert(err, IsNil)
+	s.server = server
+	s.client = dockertest.NewClientWithEngine(docker)
+}
+
+func (s *StrategiesTest) TearDownTest(c *C) {
+	c.Assert(s.server.Close(), IsNil)
+}
+
+// run starts a container whose internal port 80 is published on public
+// and returns the input a Ping would receive.
+func (s *StrategiesTest) run(c *C, image *fakeengine.Image, public uint16) *dockertest.PingInput {
+	s.server.AddImage(testImage, image)
+	input := dockertest.NewClientInput(testImage)
+	input.Ports.Add(&dockertest.Port{Private: 80, Public: public, Protocol: dockertest.ProtocolTCP})
+	info, err := s.client.RunContainer(context.Background(), input)
+	c.Assert(err, IsNil)
+	return &dockertest.PingInput{Context: context.Background(), Container: info}
+}
+
+// listen returns a listener on a free local port.
+func listen(c *C) (net.Listener, uint16) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	return listener, uint16(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func (s *StrategiesTest) TestTCP(c *C) {
+	listener, port := listen(c)
+	defer listener.Close() // nolint: errcheck
+	input := s.run(c, &fakeengine.Image{}, port)
+	c.Assert(TCP(80, quick...)(input), IsNil)
+	c.Assert(TCP(443, quick...)(input), Equals, dockertest.ErrPortNotFound)
+
+	c.Assert(listener.Close(), IsNil)
+	c.Assert(errors.Is(TCP(80, quick...)(input), ErrTimeout), Equals, true)
+}
+
+func (s *StrategiesTest) TestHTTP(c *C) {
+	listener, port := listen(c)
+	defer listener.Close() // nolint: errcheck
+	var ready int32
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { // nolint: errcheck
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if atomic.CompareAndSwapInt32(&ready, 0, 1) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"status": "ok"}`) // nolint: errcheck
+	}))
+	input := s.run(c, &fakeengine.Image{}, port)
+
+	check := NewHTTPInput(80, "health")
+	check.Body = regexp.MustCompile(`"status": "ok"`)
+	c.Assert(HTTP(check, quick...)(input), IsNil)
+
+	check = NewHTTPInput(80, "/missing")
+	err := HTTP(check, quick...)(input)
+	c.Assert(errors.Is(err, ErrTimeout), Equals, true)
+	c.Assert(err, ErrorMatches, ".*unexpected status 404, expected 200")
+
+	check = &HTTPInput{Port: 80, Path: "/health", Body: regexp.MustCompile("nope")}
+	c.Assert(HTTP(check, quick...)(input), ErrorMatches, `.*response body does not match "nope"`)
+}
+
+func (s *StrategiesTest) TestLog(c *C) {
+	input := s.run(c, &fakeengine.Image{Stdout: []string{"starting"}}, dockertest.RandomPort)
+	ready := regexp.MustCompile(`^ready to accept connections$`)
+	err := Log(ready, quick...)(input)
+	c.Assert(errors.Is(err, ErrTimeout), Equals, true)
+	c.Assert(errors.Is(err, ErrLogNotFound), Equals, false)
+
+	c.Assert(s.server.WriteStderr(input.Container.ID(), "ready to accept connections"), IsNil)
+	c.Assert(Log(ready, quick...)(input), IsNil)
+}
+
+func (s *StrategiesTest) TestHealthy(c *C) {
+	input := s.run(c, &fakeengine.Image{}, dockertest.RandomPort)
+	c.Assert(Healthy(quick...)(input), Equals, ErrNoHealthcheck)
+
+	c.Assert(s.server.SetHealth(input.Container.ID(), "starting"), IsNil)
+	c.Assert(Healthy(quick...)(input), ErrorMatches, ".*container is starting")
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		s.server.SetHealth(input.Container.ID(), "healthy") // nolint: errcheck
+	}()
+	c.Assert(Healthy(quick...)(input), IsNil)
+}
+
+func (s *StrategiesTest) TestExec(c *C) {
+	calls := 0
+	input := s.run(c, &fakeengine.Image{
+		Exec: func(exec *fakeengine.Exec) int {
+			calls++
+			if calls < 3 {
+				fmt.Fprintln(exec.Stderr, "connection refused") // nolint: errcheck
+				return 1
+			}
+			return 0
+		},
+	}, dockertest.RandomPort)
+	c.Assert(Exec([]string{"pg_isready"}, quick...)(input), IsNil)
+	c.Assert(calls, Equals, 3)
+
+	calls = -100
+	err := Exec([]string{"pg_isready"}, quick...)(input)
+	c.Assert(err, ErrorMatches, ".*pg_isready exited with 1: connection refused")
+}
+
+func (s *StrategiesTest) TestFile(c *C) {
+	input := s.run(c, &fakeengine.Image{}, dockertest.RandomPort)
+	err := File("/tmp/ready", quick...)(input)
+	c.Assert(errors.Is(err, ErrTimeout), Equals, true)
+	c.Assert(s.server.WriteFile(input.Container.ID(), "/tmp/ready", []byte("1")), IsNil)
+	c.Assert(File("/tmp/ready", quick...)(input), IsNil)
+}
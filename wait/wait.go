@@ -0,0 +1,192 @@
+// Package wait provides ready-made dockertest.Ping implementations which
+// wait for a container to become ready, along with All, Any and Sequence
+// which combine them:
+//
+//	service.Ping = wait.Sequence(
+//	    wait.TCP(5432),
+//	    wait.Log(regexp.MustCompile("ready to accept connections")),
+//	)
+//
+// Each check is retried every DefaultInterval until it succeeds or
+// DefaultTimeout passes, use WithInterval and WithTimeout to change this.
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/crewjam/errset"
+	"github.com/opalmer/dockertest"
+)
+
+const (
+	// DefaultInterval is how often checks are retried unless
+	// WithInterval is provided.
+	DefaultInterval = time.Millisecond * 100
+
+	// DefaultTimeout is how long checks are retried for unless
+	// WithTimeout is provided.
+	DefaultTimeout = time.Minute
+)
+
+var (
+	// ErrTimeout is returned when a check did not succeed before the
+	// timeout. The returned error wraps ErrTimeout and includes the
+	// last failure.
+	ErrTimeout = errors.New("timed out waiting for the container")
+
+	// ErrPingsNotProvided is returned by the Ping produced by Any if it
+	// was not given any Pings.
+	ErrPingsNotProvided = errors.New("no pings provided")
+)
+
+// options holds the settings which an Option may modify.
+type options struct {
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// Option is used to configure the Pings produced by this package.
+type Option func(*options)
+
+// WithInterval sets how long to wait between checks.
+func WithInterval(interval time.Duration) Option {
+	return func(options *options) {
+		options.interval = interval
+	}
+}
+
+// WithTimeout sets how long to keep checking before giving up.
+func WithTimeout(timeout time.Duration) Option {
+	return func(options *options) {
+		options.timeout = timeout
+	}
+}
+
+func newOptions(opts []Option) *options {
+	options := &options{interval: DefaultInterval, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// contextOf returns the context provided to the Ping or a background
+// context if one was not provided.
+func contextOf(input *dockertest.PingInput) context.Context {
+	if input.Context != nil {
+		return input.Context
+	}
+	return context.Background()
+}
+
+// withContext returns a copy of input using ctx.
+func withContext(input *dockertest.PingInput, ctx context.Context) *dockertest.PingInput {
+	copied := *input
+	copied.Context = ctx
+	return &copied
+}
+
+// fatal wraps an error which poll returns immediately rather than
+// retrying the check.
+type fatal struct {
+	error
+}
+
+// poll calls check until it succeeds, the timeout passes or the context
+// of the Ping is done.
+func poll(input *dockertest.PingInput, opts []Option, check func(ctx context.Context) error) error {
+	options := newOptions(opts)
+	parent := contextOf(input)
+	ctx, cancel := context.WithTimeout(parent, options.timeout)
+	defer cancel()
+
+	var last error
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		if failed, ok := err.(fatal); ok {
+			return failed.error
+		}
+		// A check interrupted by the timeout is less useful to report
+		// than the failure before it.
+		if last == nil || ctx.Err() == nil {
+			last = err
+		}
+		select {
+		case <-ctx.Done():
+			if parent.Err() != nil {
+				return parent.Err()
+			}
+			return fmt.Errorf("%w: %v", ErrTimeout, last)
+		case <-time.After(options.interval):
+		}
+	}
+}
+
+// All produces a Ping which runs pings concurrently and succeeds once
+// all of them have. The remaining pings are stopped after the first
+// failure which is returned.
+func All(pings ...dockertest.Ping) dockertest.Ping {
+	return func(input *dockertest.PingInput) error {
+		ctx, cancel := context.WithCancel(contextOf(input))
+		defer cancel()
+		errs := make(chan error, len(pings))
+		for _, ping := range pings {
+			go func(ping dockertest.Ping) {
+				errs <- ping(withContext(input, ctx))
+			}(ping)
+		}
+		for range pings {
+			if err := <-errs; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Any produces a Ping which runs pings concurrently and succeeds as soon
+// as one of them does, the remaining pings are then stopped. If every
+// ping fails the errors are combined.
+func Any(pings ...dockertest.Ping) dockertest.Ping {
+	return func(input *dockertest.PingInput) error {
+		if len(pings) == 0 {
+			return ErrPingsNotProvided
+		}
+		ctx, cancel := context.WithCancel(contextOf(input))
+		defer cancel()
+		errs := make(chan error, len(pings))
+		for _, ping := range pings {
+			go func(ping dockertest.Ping) {
+				errs <- ping(withContext(input, ctx))
+			}(ping)
+		}
+		errout := errset.ErrSet{}
+		for range pings {
+			err := <-errs
+			if err == nil {
+				return nil
+			}
+			errout = append(errout, err)
+		}
+		return errout.ReturnValue()
+	}
+}
+
+// Sequence produces a Ping which runs pings one after another, stopping
+// at the first failure.
+func Sequence(pings ...dockertest.Ping) dockertest.Ping {
+	return func(input *dockertest.PingInput) error {
+		for _, ping := range pings {
+			if err := ping(input); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
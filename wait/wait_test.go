@@ -0,0 +1,105 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opalmer/dockertest"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type WaitTest struct{}
+
+var _ = Suite(&WaitTest{})
+
+func succeed(input *dockertest.PingInput) error {
+	return nil
+}
+
+func fail(input *dockertest.PingInput) error {
+	return errors.New("failed")
+}
+
+// blocked waits for the context of the Ping to be done.
+func blocked(input *dockertest.PingInput) error {
+	<-input.Context.Done()
+	return input.Context.Err()
+}
+
+func (s *WaitTest) TestPoll(c *C) {
+	var calls int32
+	err := poll(&dockertest.PingInput{}, []Option{WithInterval(time.Millisecond)}, func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(3))
+}
+
+func (s *WaitTest) TestPollTimeout(c *C) {
+	opts := []Option{WithInterval(time.Millisecond), WithTimeout(time.Millisecond * 20)}
+	err := poll(&dockertest.PingInput{}, opts, func(ctx context.Context) error {
+		return errors.New("not yet")
+	})
+	c.Assert(errors.Is(err, ErrTimeout), Equals, true)
+	c.Assert(err, ErrorMatches, ".*: not yet")
+}
+
+func (s *WaitTest) TestPollFatal(c *C) {
+	var calls int32
+	err := poll(&dockertest.PingInput{}, []Option{WithInterval(time.Millisecond)}, func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return fatal{ErrNoHealthcheck}
+	})
+	c.Assert(err, Equals, ErrNoHealthcheck)
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(1))
+}
+
+func (s *WaitTest) TestPollCancelled(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := poll(&dockertest.PingInput{Context: ctx}, nil, func(ctx context.Context) error {
+		return errors.New("not yet")
+	})
+	c.Assert(err, Equals, context.Canceled)
+}
+
+func (s *WaitTest) TestAll(c *C) {
+	c.Assert(All()(&dockertest.PingInput{}), IsNil)
+	c.Assert(All(succeed, succeed)(&dockertest.PingInput{}), IsNil)
+	// The blocked ping is stopped once another fails.
+	c.Assert(All(succeed, blocked, fail)(&dockertest.PingInput{}), ErrorMatches, "failed")
+}
+
+func (s *WaitTest) TestAny(c *C) {
+	c.Assert(Any()(&dockertest.PingInput{}), Equals, ErrPingsNotProvided)
+	// The blocked ping is stopped once another succeeds.
+	c.Assert(Any(fail, blocked, succeed)(&dockertest.PingInput{}), IsNil)
+	err := Any(fail, fail)(&dockertest.PingInput{})
+	c.Assert(err, ErrorMatches, "failed(\n|.)*failed")
+}
+
+func (s *WaitTest) TestSequence(c *C) {
+	order := []int{}
+	record := func(n int) dockertest.Ping {
+		return func(input *dockertest.PingInput) error {
+			order = append(order, n)
+			return nil
+		}
+	}
+	c.Assert(Sequence(record(1), record(2), record(3))(&dockertest.PingInput{}), IsNil)
+	c.Assert(order, DeepEquals, []int{1, 2, 3})
+
+	order = []int{}
+	c.Assert(Sequence(record(1), fail, record(3))(&dockertest.PingInput{}), ErrorMatches, "failed")
+	c.Assert(order, DeepEquals, []int{1})
+}
@@ -0,0 +1,91 @@
+package dockertest
+
+import (
+	"context"
+	"time"
+
+	"github.com/opalmer/dockertest/fakeengine"
+	. "gopkg.in/check.v1"
+)
+
+type WaitTest struct{}
+
+var _ = Suite(&WaitTest{})
+
+func (s *WaitTest) TestWait(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		c.Check(server.Exit(info.ID(), 3), IsNil)
+	}()
+	result, err := info.Wait(context.Background(), "")
+	c.Assert(err, IsNil)
+	c.Assert(result, DeepEquals, &WaitResult{ExitCode: 3})
+	c.Assert(info.State.Running, Equals, false)
+	c.Assert(info.ExitCode(), Equals, 3)
+
+	// The container has already stopped so this returns immediately.
+	result, err = info.Wait(context.Background(), WaitNotRunning)
+	c.Assert(err, IsNil)
+	c.Assert(result.ExitCode, Equals, 3)
+}
+
+func (s *WaitTest) TestWaitOneShot(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{Exits: true, ExitCode: 2})
+
+	result, err := info.Wait(context.Background(), WaitNotRunning)
+	c.Assert(err, IsNil)
+	c.Assert(result.ExitCode, Equals, 2)
+}
+
+func (s *WaitTest) TestWaitOOMKilled(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	c.Assert(server.OOMKill(info.ID()), IsNil)
+	result, err := info.Wait(context.Background(), WaitNotRunning)
+	c.Assert(err, IsNil)
+	c.Assert(result.ExitCode, Equals, 137)
+	c.Assert(info.OOMKilled(), Equals, true)
+}
+
+func (s *WaitTest) TestWaitRemoved(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		c.Check(dc.RemoveContainer(context.Background(), info.ID()), IsNil)
+	}()
+	result, err := info.Wait(context.Background(), WaitRemoved)
+	c.Assert(err, IsNil)
+	c.Assert(result.ExitCode, Equals, 137)
+}
+
+func (s *WaitTest) TestWaitContextDone(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	_, err := info.Wait(ctx, WaitNextExit)
+	c.Assert(err, ErrorMatches, ".*context deadline exceeded")
+}
+
+func (s *WaitTest) TestWaitNoSuchContainer(c *C) {
+	dc, server := newFakeClient(c)
+	defer server.Close() // nolint: errcheck
+	info := runFakeContainer(c, dc, server, &fakeengine.Image{})
+	c.Assert(dc.RemoveContainer(context.Background(), info.ID()), IsNil)
+
+	_, err := info.Wait(context.Background(), WaitNotRunning)
+	c.Assert(err, ErrorMatches, ".*No such container.*")
+}